@@ -3,7 +3,12 @@ package actions
 import (
 	"testing"
 
+	"github.com/gobuffalo/envy"
 	"github.com/gobuffalo/suite/v3"
+
+	"github.com/hyeoncheon/honcheonui/models"
+	"github.com/hyeoncheon/honcheonui/plugins"
+	"github.com/hyeoncheon/honcheonui/plugins/fake"
 )
 
 type ActionSuite struct {
@@ -14,3 +19,36 @@ func Test_ActionSuite(t *testing.T) {
 	as := &ActionSuite{suite.NewAction(App())}
 	suite.Run(t, as)
 }
+
+// login creates a member and sets it on the session.
+func (as *ActionSuite) login() *models.Member {
+	member := &models.Member{Email: "tester@example.com"}
+	as.NoError(as.DB.Create(member))
+	as.Session.Set("member_id", member.ID)
+	return member
+}
+
+// fakeProvider registers the fake provider plugin with builtin default
+// fixture and returns a provider entity of the member for its account.
+func (as *ActionSuite) fakeProvider(member *models.Member) *models.Provider {
+	envy.Set("HCU_SECRET_KEY", "test-secret-key")
+	as.NoError(plugins.Register(plugins.ClassProvider, fake.Name, fake.New("")))
+
+	provider := &models.Provider{
+		MemberID: member.ID,
+		Provider: fake.Name,
+		User:     "fake",
+		Pass:     "fake",
+		GroupID:  "100",
+		UserID:   "1001",
+	}
+	as.NoError(as.DB.Create(provider))
+	return provider
+}
+
+// queuedJob returns the queued job of the handler.
+func (as *ActionSuite) queuedJob(handler string) *models.Job {
+	job := &models.Job{}
+	as.NoError(as.DB.Where("handler = ?", handler).Order("created_at").First(job))
+	return job
+}
@@ -2,17 +2,62 @@ package actions
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
 
+	"github.com/hyeoncheon/honcheonui/models"
+	"github.com/hyeoncheon/honcheonui/plugins"
 	"github.com/hyeoncheon/honcheonui/workers"
 )
 
 // AdminHandler render admin page.
 func AdminHandler(c buffalo.Context) error {
+	c.Set("provider_plugins", plugins.List(plugins.ClassProvider))
+	c.Set("notifier_plugins", plugins.List(plugins.ClassNotifier))
+	c.Set("dead_letters", models.RecentDeadLetters(20))
+	if err := setScheduleForm(c); err != nil {
+		return err
+	}
+	rules, err := models.AllCorrelationRules()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c.Set("correlation_rules", rules)
+	c.Set("correlation_rule", &models.CorrelationRule{Window: "1h", ByResource: true}) // for form
 	return c.Render(http.StatusOK, r.HTML("admin.html"))
 }
 
+// setScheduleForm sets schedules and options for the schedule form.
+func setScheduleForm(c buffalo.Context) error {
+	schedules, err := models.AllSchedules()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	providers := &models.Providers{}
+	if err := models.DB.All(providers); err != nil {
+		return errors.WithStack(err)
+	}
+
+	workerOptions := make(map[string]string)
+	for _, w := range workers.ScheduledWorkers() {
+		workerOptions[w.Name] = w.Name
+	}
+	providerOptions := map[string]string{t(c, "All.Providers"): uuid.Nil.String()}
+	for _, p := range *providers {
+		providerOptions[p.String()] = p.ID.String()
+	}
+	c.Set("schedules", schedules)
+	c.Set("scheduled_workers", workers.ScheduledWorkers())
+	c.Set("schedule", &models.Schedule{}) // for form
+	c.Set("worker_options", workerOptions)
+	c.Set("provider_options", providerOptions)
+	return nil
+}
+
 // AdminSyncNotification queues notification sync job and redirect to admin root.
 func AdminSyncNotification(c buffalo.Context) error {
 	if err := workers.Run(workers.WorkerNotificationWatch, nil); err != nil {
@@ -23,3 +68,192 @@ func AdminSyncNotification(c buffalo.Context) error {
 
 	return c.Redirect(http.StatusSeeOther, "/admin")
 }
+
+// AdminReloadPlugins reloads all plugins and redirect to admin root.
+func AdminReloadPlugins(c buffalo.Context) error {
+	if err := plugins.Reload(); err != nil {
+		c.Flash().Add("danger", t(c, "Could.not.reload.plugins"))
+	} else {
+		c.Flash().Add("success", t(c, "Plugins.are.reloaded"))
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin")
+}
+
+// AdminRequeueDeadLetter queues the job of the dead letter again and
+// redirect to admin root.
+func AdminRequeueDeadLetter(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+	letter := &models.DeadLetter{}
+	if err := tx.Find(letter, c.Param("dead_letter_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if err := workers.Requeue(tx, letter); err != nil {
+		// the dead letter is kept by rolling back the transaction
+		return c.Error(http.StatusInternalServerError, err)
+	}
+	c.Flash().Add("success", t(c, "The.job.was.requeued"))
+
+	return c.Redirect(http.StatusSeeOther, "/admin")
+}
+
+// AdminSaveSchedule creates or updates the schedule of the worker for the
+// provider and redirect to admin root.
+func AdminSaveSchedule(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+	form := &models.Schedule{}
+	if err := c.Bind(form); err != nil {
+		return errors.WithStack(err)
+	}
+
+	schedule := &models.Schedule{}
+	err := tx.Where("worker = ? AND provider_id = ?", form.Worker, form.ProviderID).First(schedule)
+	if err != nil {
+		schedule = form
+	}
+	schedule.Cron = form.Cron
+	verrs, err := tx.ValidateAndSave(schedule)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.Error())
+	} else {
+		c.Flash().Add("success", t(c, "Schedule.was.saved"))
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin")
+}
+
+// AdminDestroySchedule deletes the schedule and redirect to admin root.
+// The worker falls back to its default schedule.
+func AdminDestroySchedule(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+	schedule := &models.Schedule{}
+	if err := tx.Find(schedule, c.Param("schedule_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if err := tx.Destroy(schedule); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", t(c, "Schedule.was.deleted"))
+	return c.Redirect(http.StatusSeeOther, "/admin")
+}
+
+// AdminCreateCorrelationRule adds a correlation rule and redirect to admin
+// root. It applies to incidents reported after it is added.
+func AdminCreateCorrelationRule(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+	rule := &models.CorrelationRule{}
+	if err := c.Bind(rule); err != nil {
+		return errors.WithStack(err)
+	}
+
+	verrs, err := tx.ValidateAndCreate(rule)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.Error())
+	} else {
+		c.Flash().Add("success", t(c, "Correlation.rule.was.added"))
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin")
+}
+
+// AdminDestroyCorrelationRule deletes the correlation rule and redirect to
+// admin root. Incidents already grouped by the rule are kept as they are.
+func AdminDestroyCorrelationRule(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+	rule := &models.CorrelationRule{}
+	if err := tx.Find(rule, c.Param("correlation_rule_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if err := tx.Destroy(rule); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", t(c, "Correlation.rule.was.deleted"))
+	return c.Redirect(http.StatusSeeOther, "/admin")
+}
+
+// AdminWorkers renders statuses of all workers as HTML or JSON.
+func AdminWorkers(c buffalo.Context) error {
+	statuses := workers.Statuses()
+	if wantsJSON(c) {
+		return c.Render(http.StatusOK, r.JSON(statuses))
+	}
+	c.Set("statuses", statuses)
+	return c.Render(http.StatusOK, r.HTML("workers/index.html"))
+}
+
+// AdminWorker renders the status of the worker as JSON.
+func AdminWorker(c buffalo.Context) error {
+	status, err := workers.GetStatus(c.Param("worker_name"))
+	if err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	return c.Render(http.StatusOK, r.JSON(status))
+}
+
+// AdminWorkerRun queues the worker to be run immediately.
+func AdminWorkerRun(c buffalo.Context) error {
+	return controlWorker(c, workers.RunNow, "Worker.will.be.run.in.background")
+}
+
+// AdminWorkerPause pauses scheduled runs of the worker.
+func AdminWorkerPause(c buffalo.Context) error {
+	return controlWorker(c, workers.Pause, "Worker.was.paused")
+}
+
+// AdminWorkerResume resumes scheduled runs of the worker.
+func AdminWorkerResume(c buffalo.Context) error {
+	return controlWorker(c, workers.Resume, "Worker.was.resumed")
+}
+
+// controlWorker runs the control function for the worker and responds with
+// the status of the worker as JSON, or redirect to the worker page.
+func controlWorker(c buffalo.Context, fn func(string) error, message string) error {
+	name := c.Param("worker_name")
+	err := fn(name)
+	if wantsJSON(c) {
+		if err == workers.ErrNoSuchWorker {
+			return c.Error(http.StatusNotFound, err)
+		}
+		if err != nil {
+			return c.Render(http.StatusUnprocessableEntity, r.JSON(map[string]string{"error": err.Error()}))
+		}
+		status, _ := workers.GetStatus(name)
+		return c.Render(http.StatusOK, r.JSON(status))
+	}
+
+	if err != nil {
+		c.Flash().Add("danger", t(c, "Could.not.control.worker")+": "+err.Error())
+	} else {
+		c.Flash().Add("success", t(c, message))
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/workers")
+}
+
+// wantsJSON returns true if the client requested JSON response.
+func wantsJSON(c buffalo.Context) bool {
+	return c.Param("format") == "json" ||
+		strings.Contains(c.Request().Header.Get("Accept"), "application/json")
+}
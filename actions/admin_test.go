@@ -0,0 +1,47 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/gobuffalo/envy"
+
+	"github.com/hyeoncheon/honcheonui/models"
+	"github.com/hyeoncheon/honcheonui/workers"
+)
+
+func (as *ActionSuite) Test_AdminSyncNotification() {
+	envy.Set("HCU_NOTIFICATION_LOOKBACK", "87600h")
+	defer envy.Set("HCU_NOTIFICATION_LOOKBACK", "")
+	provider := as.fakeProvider(as.login())
+
+	res := as.HTML("/admin/sync/notification").Get()
+	as.Equal(http.StatusSeeOther, res.Code)
+	as.Equal("/admin", res.Location())
+
+	// the queued job fans out a job for each provider
+	args, err := as.queuedJob(workers.WorkerNotificationWatch).ArgsMap()
+	as.NoError(err)
+	as.Nil(args["provider_id"])
+	as.NoError(workers.NotificationWatch{}.Handler(args))
+
+	job := &models.Job{}
+	as.NoError(as.DB.Where("handler = ? AND args <> ?", workers.WorkerNotificationWatch, "{}").First(job))
+	args, err = job.ArgsMap()
+	as.NoError(err)
+	as.Equal(provider.ID.String(), args["provider_id"])
+
+	// malformed notifications of the default fixture are skipped and the
+	// others are saved
+	as.NoError(workers.NotificationWatch{}.Handler(args))
+	count, err := as.DB.Count(&models.Incidents{})
+	as.NoError(err)
+	as.Equal(2, count)
+}
+
+func (as *ActionSuite) Test_AdminReloadPlugins() {
+	as.login()
+
+	res := as.HTML("/admin/plugins/reload").Post(map[string]string{})
+	as.Equal(http.StatusSeeOther, res.Code)
+	as.Equal("/admin", res.Location())
+}
@@ -0,0 +1,90 @@
+package actions
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+// AlertRulesResource is the resource for the AlertRule model
+type AlertRulesResource struct {
+	buffalo.Resource
+}
+
+// Create adds an AlertRule to the DB and redirects to the service. The
+// scope of the rule is given as "service:<id>" or "tag:<id>" and it should
+// be the service or one of its tags.
+func (v AlertRulesResource) Create(c buffalo.Context) error {
+	tx, service, err := setService(c)
+	if err != nil {
+		return err
+	}
+	if err := tx.Load(service, "Tags"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	rule := &models.AlertRule{}
+	if err := c.Bind(rule); err != nil {
+		return errors.WithStack(err)
+	}
+	rule.ServiceID = uuid.Nil
+	rule.TagID = uuid.Nil
+	scope := strings.SplitN(c.Param("Scope"), ":", 2)
+	if len(scope) == 2 {
+		id, _ := uuid.FromString(scope[1])
+		switch scope[0] {
+		case "service":
+			rule.ServiceID = id
+		case "tag":
+			rule.TagID = id
+		}
+	}
+
+	if !service.HasAlertRule(rule) {
+		c.Flash().Add("danger", t(c, "Alert.rule.scope.is.invalid"))
+		return c.Redirect(http.StatusSeeOther, "/services/"+service.ID.String())
+	}
+
+	verrs, err := tx.ValidateAndCreate(rule)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.Error())
+	} else {
+		c.Flash().Add("success", t(c, "Alert.rule.was.added"))
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/services/"+service.ID.String())
+}
+
+// Destroy deletes an AlertRule of the service from the DB and redirects to
+// the service. Rules out of the scope of the service are not found.
+func (v AlertRulesResource) Destroy(c buffalo.Context) error {
+	tx, service, err := setService(c)
+	if err != nil {
+		return err
+	}
+	if err := tx.Load(service, "Tags"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	rule := &models.AlertRule{}
+	if err := tx.Find(rule, c.Param("alert_rule_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+	if !service.HasAlertRule(rule) {
+		return c.Error(http.StatusNotFound, errors.New("alert rule is not found on the service"))
+	}
+	if err := tx.Destroy(rule); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", t(c, "Alert.rule.was.deleted"))
+	return c.Redirect(http.StatusSeeOther, "/services/"+service.ID.String())
+}
@@ -0,0 +1,42 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+func (as *ActionSuite) Test_AlertRulesResource_Scope() {
+	as.login()
+	tag := &models.Tag{Name: "web"}
+	as.NoError(as.DB.Create(tag))
+	service := &models.Service{Name: "shop"}
+	another := &models.Service{Name: "blog"}
+	as.NoError(as.DB.Create(service))
+	as.NoError(as.DB.Create(another))
+	as.NoError(as.DB.Create(&models.ServicesTags{ServiceID: service.ID, TagID: tag.ID}))
+
+	// rules could be scoped to the service or its tags only
+	for i, scope := range []string{
+		"service:" + another.ID.String(),
+		"tag:" + tag.ID.String(),
+		"service:" + service.ID.String(),
+	} {
+		res := as.HTML("/services/%v/alert_rules", service.ID).Post(map[string]string{
+			"Name": "missing", "Condition": models.AlertMissing, "Scope": scope,
+		})
+		as.Equal(http.StatusSeeOther, res.Code)
+		count, err := as.DB.Count(&models.AlertRules{})
+		as.NoError(err)
+		as.Equal(i, count, scope)
+	}
+
+	// rules of the service could not be deleted via another service
+	rule := &models.AlertRule{}
+	as.NoError(as.DB.Where("tag_id = ?", tag.ID).First(rule))
+	res := as.HTML("/services/%v/alert_rules/%v", another.ID, rule.ID).Delete()
+	as.Equal(http.StatusNotFound, res.Code)
+	res = as.HTML("/services/%v/alert_rules/%v", service.ID, rule.ID).Delete()
+	as.Equal(http.StatusSeeOther, res.Code)
+	as.Equal("/services/"+service.ID.String(), res.Location())
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/markbates/goth/gothic"
 
 	"github.com/hyeoncheon/honcheonui/models"
+	"github.com/hyeoncheon/honcheonui/plugins"
 	"github.com/hyeoncheon/honcheonui/workers"
 )
 
@@ -29,9 +30,13 @@ func App() *buffalo.App {
 		app = buffalo.New(buffalo.Options{
 			Env:         ENV,
 			SessionName: "_honcheonui_session",
+			Worker:      workers.NewBackend(),
 			// TODO: add secure session store. should it be redis?
 		})
 
+		if err := plugins.InitPlugins(app); err != nil {
+			app.Logger.Errorf("error while initializing plugins: %v", err)
+		}
 		if err := workers.InitWorkers(app); err != nil {
 			app.Logger.Errorf("error while initializing workers: %v", err)
 		}
@@ -80,13 +85,32 @@ func App() *buffalo.App {
 		app.GET("/resources/{resource_id}", ResourcesResource{}.Show)
 		app.GET("/resources/{resource_id}/sync", ResourcesResource{}.Update)
 		app.DELETE("/resources/{resource_id}", ResourcesResource{}.Destroy)
+		app.GET("/change_sets", ChangeSetsResource{}.List)
+		app.GET("/change_sets/{change_set_id}", ChangeSetsResource{}.Show)
 		app.Resource("/services", ServicesResource{})
 		app.POST("/services/{service_id}/add_tags", ServicesResource{}.AddTags)
+		app.POST("/services/{service_id}/alert_rules", AlertRulesResource{}.Create)
+		app.DELETE("/services/{service_id}/alert_rules/{alert_rule_id}", AlertRulesResource{}.Destroy)
+		app.GET("/incidents", IncidentsResource{}.List)
 		app.GET("/incidents/{incident_id}", IncidentsResource{}.Show)
+		app.POST("/incidents/{incident_id}/transition", IncidentsResource{}.Transition)
+		app.POST("/incidents/{incident_id}/comments", IncidentsResource{}.Comment)
+		app.GET("/incidents/{incident_id}/timeline", IncidentsResource{}.Timeline)
 
 		admin := app.Group("/admin")
 		admin.GET("/", AdminHandler)
 		admin.GET("/sync/notification", AdminSyncNotification)
+		admin.POST("/plugins/reload", AdminReloadPlugins)
+		admin.POST("/dead_letters/{dead_letter_id}/requeue", AdminRequeueDeadLetter)
+		admin.POST("/schedules", AdminSaveSchedule)
+		admin.DELETE("/schedules/{schedule_id}", AdminDestroySchedule)
+		admin.POST("/correlation_rules", AdminCreateCorrelationRule)
+		admin.DELETE("/correlation_rules/{correlation_rule_id}", AdminDestroyCorrelationRule)
+		admin.GET("/workers", AdminWorkers)
+		admin.GET("/workers/{worker_name}", AdminWorker)
+		admin.POST("/workers/{worker_name}/run", AdminWorkerRun)
+		admin.POST("/workers/{worker_name}/pause", AdminWorkerPause)
+		admin.POST("/workers/{worker_name}/resume", AdminWorkerResume)
 
 		app.ServeFiles("/", assetsBox) // serve files from the public directory
 	}
@@ -0,0 +1,53 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+// ChangeSetsResource is the resource for the ChangeSet model
+type ChangeSetsResource struct {
+	buffalo.Resource
+}
+
+// List gets all ChangeSets. It could be filtered by provider_id.
+//! MANAGER ONLY, currently not protected
+func (v ChangeSetsResource) List(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	changeSets := &models.ChangeSets{}
+	q := tx.PaginateFromParams(c.Params()).Order("created_at desc")
+	if id := c.Param("provider_id"); id != "" {
+		q = q.Where("provider_id = ?", id)
+	}
+	if err := q.All(changeSets); err != nil {
+		return errors.WithStack(err)
+	}
+	tx.Load(changeSets, "Provider")
+
+	c.Set("pagination", q.Paginator)
+	return c.Render(http.StatusOK, r.Auto(c, changeSets))
+}
+
+// Show gets the data for one ChangeSet.
+func (v ChangeSetsResource) Show(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	changeSet := &models.ChangeSet{}
+	if err := tx.Eager().Find(changeSet, c.Param("change_set_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	return c.Render(http.StatusOK, r.Auto(c, changeSet))
+}
@@ -4,10 +4,13 @@ import (
 	"net/http"
 
 	"github.com/gobuffalo/buffalo"
+
+	"github.com/hyeoncheon/honcheonui/models"
 )
 
 // HomeHandler is a default handler to serve up a home page.
 func HomeHandler(c buffalo.Context) error {
+	c.Set("sync_runs", models.RecentSyncRuns(10))
 	return c.Render(http.StatusOK, r.HTML("index.html"))
 }
 
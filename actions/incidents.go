@@ -2,19 +2,101 @@ package actions
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 
 	"github.com/hyeoncheon/honcheonui/models"
 )
 
+// dateFormat is the format of date params
+const dateFormat = "2006-01-02"
+
+// incidentFilterParams is the list of params for the incident filter
+var incidentFilterParams = []string{
+	"provider", "category", "type", "state", "open",
+	"issued_from", "issued_to", "resource_id", "service_id", "q",
+}
+
 // IncidentsResource is the resource for the Incident model
 type IncidentsResource struct {
 	buffalo.Resource
 }
 
+// List gets Incidents matching the filter in the params, newest first.
+func (v IncidentsResource) List(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	filter := incidentFilter(c)
+	incidents := &models.Incidents{}
+	q := filter.Apply(tx.PaginateFromParams(c.Params())).Order("incidents.issued_at desc")
+	if err := q.All(incidents); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !wantsJSON(c) {
+		services := &models.Services{}
+		if err := tx.Order("name").All(services); err != nil {
+			return errors.WithStack(err)
+		}
+		serviceOptions := map[string]string{"": ""}
+		for _, s := range *services {
+			serviceOptions[s.Name] = s.ID.String()
+		}
+		c.Set("service_options", serviceOptions)
+	}
+	params := map[string]string{}
+	for _, key := range incidentFilterParams {
+		params[key] = c.Param(key)
+	}
+	c.Set("filter", params)
+	c.Set("is_filtered", !filter.IsEmpty())
+	c.Set("pagination", q.Paginator)
+	return c.Render(http.StatusOK, r.Auto(c, incidents))
+}
+
+// incidentFilter returns the incident filter from params. Invalid values
+// are ignored with a flash message. Dates are in YYYY-MM-DD format and the
+// issued_to date is inclusive.
+func incidentFilter(c buffalo.Context) models.IncidentFilter {
+	filter := models.IncidentFilter{
+		Provider: c.Param("provider"),
+		Category: c.Param("category"),
+		Type:     c.Param("type"),
+		State:    c.Param("state"),
+		Query:    strings.TrimSpace(c.Param("q")),
+	}
+	if open, err := strconv.ParseBool(c.Param("open")); err == nil {
+		filter.IsOpen = &open
+	}
+	if from, err := time.Parse(dateFormat, c.Param("issued_from")); err == nil {
+		filter.IssuedFrom = from
+	} else if c.Param("issued_from") != "" {
+		c.Flash().Add("danger", t(c, "Invalid.date")+": "+c.Param("issued_from"))
+	}
+	if to, err := time.Parse(dateFormat, c.Param("issued_to")); err == nil {
+		filter.IssuedTo = to.AddDate(0, 0, 1)
+	} else if c.Param("issued_to") != "" {
+		c.Flash().Add("danger", t(c, "Invalid.date")+": "+c.Param("issued_to"))
+	}
+	if id, err := uuid.FromString(c.Param("resource_id")); err == nil {
+		filter.ResourceID = id
+	}
+	if id, err := uuid.FromString(c.Param("service_id")); err == nil {
+		filter.ServiceID = id
+	}
+	return filter
+}
+
 // Show gets the data for one Incident.
 func (v IncidentsResource) Show(c buffalo.Context) error {
 	tx, ok := c.Value("tx").(*pop.Connection)
@@ -27,5 +109,103 @@ func (v IncidentsResource) Show(c buffalo.Context) error {
 		return c.Error(http.StatusNotFound, err)
 	}
 
+	if !wantsJSON(c) {
+		parent := &models.Incident{}
+		if incident.HasParent() {
+			if err := tx.Find(parent, incident.ParentID); err != nil {
+				c.Logger().Warnf("could not find parent of incident %v: %v", incident.ID, err)
+			}
+		}
+		children := &models.Incidents{}
+		if err := tx.Where("parent_id = ?", incident.ID).Order("issued_at").All(children); err != nil {
+			return errors.WithStack(err)
+		}
+		c.Set("parent", parent)
+		c.Set("children", children)
+	}
 	return c.Render(http.StatusOK, r.Auto(c, incident))
 }
+
+// Transition moves the Incident to the state requested by the member and
+// redirects to the incident.
+func (v IncidentsResource) Transition(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	incident := &models.Incident{}
+	if err := tx.Find(incident, c.Param("incident_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	by, _ := c.Session().Get("member_mail").(string)
+	err := incident.Transition(tx, c.Param("State"), by, strings.TrimSpace(c.Param("Resolution")))
+	switch err {
+	case nil:
+		c.Flash().Add("success", t(c, "Incident.state.was.changed"))
+	case models.ErrInvalidTransition:
+		c.Flash().Add("danger", t(c, "Invalid.state.transition"))
+	case models.ErrResolutionRequired:
+		c.Flash().Add("danger", t(c, "Resolution.is.required"))
+	default:
+		if verrs, ok := err.(*validate.Errors); ok {
+			c.Flash().Add("danger", verrs.Error())
+		} else {
+			return errors.WithStack(err)
+		}
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/incidents/"+incident.ID.String())
+}
+
+// Comment adds a comment of the member to the timeline of the Incident.
+// It renders the created event for JSON requests, or redirects to the
+// incident.
+func (v IncidentsResource) Comment(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	incident := &models.Incident{}
+	if err := tx.Find(incident, c.Param("incident_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	by, _ := c.Session().Get("member_mail").(string)
+	event, err := incident.AddEvent(tx, models.EventComment, by, strings.TrimSpace(c.Param("Message")))
+	if verrs, ok := err.(*validate.Errors); ok {
+		if wantsJSON(c) {
+			return c.Render(http.StatusUnprocessableEntity, r.JSON(verrs))
+		}
+		c.Flash().Add("danger", verrs.Error())
+	} else if err != nil {
+		return errors.WithStack(err)
+	} else if wantsJSON(c) {
+		return c.Render(http.StatusCreated, r.JSON(event))
+	} else {
+		c.Flash().Add("success", t(c, "Comment.was.added"))
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/incidents/"+incident.ID.String())
+}
+
+// Timeline renders comments and system events of the Incident as JSON.
+func (v IncidentsResource) Timeline(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	incident := &models.Incident{}
+	if err := tx.Find(incident, c.Param("incident_id")); err != nil {
+		return c.Error(http.StatusNotFound, err)
+	}
+
+	events, err := incident.Timeline(tx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return c.Render(http.StatusOK, r.JSON(events))
+}
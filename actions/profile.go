@@ -32,13 +32,14 @@ func ProfileSettings(c buffalo.Context) error {
 	tx.Load(&currentMember.Providers, "Member")
 
 	supportedProviders := make(map[string]string)
-	for _, p := range plugins.GetPluginList(c, "provider") {
+	for _, p := range plugins.GetPluginList("provider") {
 		supportedProviders[p] = p
 	}
 	c.Set("providers", currentMember.Providers)
 	c.Set("provider", &models.Provider{}) // for modal form
 	c.Set("uart_url", os.Getenv("UART_URL"))
 	c.Set("supported_providers", supportedProviders)
+	c.Set("provider_plugins", plugins.List("provider"))
 	return c.Render(200, r.HTML("profile/settings.html"))
 }
 
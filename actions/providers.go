@@ -54,7 +54,11 @@ func (v ProvidersResource) Create(c buffalo.Context) error {
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	uid, aid, err := plugin.CheckAccount(provider.User, provider.Pass)
+	user, pass, err := provider.Credentials()
+	if err != nil {
+		return c.Render(http.StatusUnprocessableEntity, r.String("credential error: %v", err))
+	}
+	uid, aid, err := plugin.CheckAccount(user, pass)
 	if err != nil {
 		return c.Render(http.StatusUnprocessableEntity, r.String("plugin error: %v", err))
 	}
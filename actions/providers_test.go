@@ -1,5 +1,12 @@
 package actions
 
+import (
+	"net/http"
+
+	"github.com/hyeoncheon/honcheonui/models"
+	"github.com/hyeoncheon/honcheonui/workers"
+)
+
 func (as *ActionSuite) Test_ProvidersResource_List() {
 	as.Fail("Not Implemented!")
 }
@@ -11,3 +18,24 @@ func (as *ActionSuite) Test_ProvidersResource_Create() {
 func (as *ActionSuite) Test_ProvidersResource_Destroy() {
 	as.Fail("Not Implemented!")
 }
+
+func (as *ActionSuite) Test_ProvidersResource_Sync() {
+	provider := as.fakeProvider(as.login())
+
+	res := as.HTML("/providers/%v/sync", provider.ID).Get()
+	as.Equal(http.StatusSeeOther, res.Code)
+	as.Equal("/settings", res.Location())
+
+	args, err := as.queuedJob(workers.WorkerResourceSync).ArgsMap()
+	as.NoError(err)
+	as.Equal(provider.ID.String(), args["provider_id"])
+
+	// run the queued job against the fake provider
+	as.NoError(workers.ResourceSync{}.Handler(args))
+	count, err := as.DB.Count(&models.Resources{})
+	as.NoError(err)
+	as.Equal(3, count)
+	run := provider.LastSyncRun(models.SyncResource)
+	as.NotNil(run)
+	as.False(run.IsFailed())
+}
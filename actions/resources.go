@@ -22,10 +22,26 @@ func (v ResourcesResource) List(c buffalo.Context) error {
 
 	resources := &models.Resources{}
 	q := tx.PaginateFromParams(c.Params())
+	if state := c.Param("state"); state != "" {
+		q = q.Where("state = ?", state)
+	}
+	// attribute filters such as filter=cpu>=4&filter=memory>8192
+	filters := []string{}
+	for _, expr := range c.Request().URL.Query()["filter"] {
+		f, err := models.ParseAttributeFilter(expr)
+		if err != nil {
+			c.Flash().Add("danger", err.Error())
+			continue
+		}
+		q = f.Apply(q)
+		filters = append(filters, f.String())
+	}
 	if err := q.All(resources); err != nil {
 		return errors.WithStack(err)
 	}
 
+	c.Set("state", c.Param("state"))
+	c.Set("filters", filters)
 	c.Set("pagination", q.Paginator)
 	return c.Render(200, r.Auto(c, resources))
 }
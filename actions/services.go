@@ -40,8 +40,20 @@ func (v ServicesResource) Show(c buffalo.Context) error {
 	}
 	tx.Load(service, "Member", "Tags")
 
-	c.Set("incidents", service.Incidents())
-	c.Set("resources", service.TaggedResources())
+	c.Set("incident_groups", models.GroupIncidents(*service.Incidents()))
+	c.Set("alert_rules", service.AlertRules())
+	c.Set("alert_rule", &models.AlertRule{}) // for modal form
+	c.Set("condition_options", conditionOptions(c))
+	scopeOptions := map[string]string{t(c, "This.Service"): "service:" + service.ID.String()}
+	for _, tag := range service.Tags {
+		scopeOptions[t(c, "Tag")+": "+tag.Name] = "tag:" + tag.ID.String()
+	}
+	c.Set("scope_options", scopeOptions)
+	if c.Param("all") == "true" {
+		c.Set("resources", service.TaggedResources(models.ResourceActive, models.ResourceMissing, models.ResourceRetired))
+	} else {
+		c.Set("resources", service.TaggedResources())
+	}
 	c.Set("tags", effectiveMember(c).GroupTags())
 	return c.Render(200, r.Auto(c, service))
 }
@@ -160,3 +172,12 @@ func setService(c buffalo.Context) (*pop.Connection, *models.Service, error) {
 	}
 	return tx, service, nil
 }
+
+// conditionOptions returns translated conditions of alert rules for forms.
+func conditionOptions(c buffalo.Context) map[string]string {
+	options := map[string]string{}
+	for _, cond := range models.AlertConditions {
+		options[t(c, "Alert."+cond)] = cond
+	}
+	return options
+}
@@ -1,7 +1,13 @@
 package grifts
 
 import (
+	"errors"
+	"fmt"
+
+	"github.com/gobuffalo/envy"
 	"github.com/markbates/grift/grift"
+
+	"github.com/hyeoncheon/honcheonui/models"
 )
 
 var _ = grift.Namespace("db", func() {
@@ -12,4 +18,18 @@ var _ = grift.Namespace("db", func() {
 		return nil
 	})
 
+	grift.Desc("rotate_key", "Re-encrypts provider credentials with HCU_SECRET_KEY. Set the previous key as HCU_OLD_SECRET_KEY")
+	grift.Add("rotate_key", func(c *grift.Context) error {
+		newKey := envy.Get("HCU_SECRET_KEY", "")
+		if newKey == "" {
+			return errors.New("HCU_SECRET_KEY is not set")
+		}
+		count, err := models.RotateProviderKey(envy.Get("HCU_OLD_SECRET_KEY", ""), newKey)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%v providers were re-encrypted\n", count)
+		return nil
+	})
+
 })
@@ -0,0 +1,31 @@
+package grifts
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/markbates/grift/grift"
+
+	"github.com/hyeoncheon/honcheonui/actions"
+	"github.com/hyeoncheon/honcheonui/workers"
+)
+
+var _ = grift.Namespace("workers", func() {
+
+	grift.Desc("schedule", "Starts the scheduler and workers in any environment until interrupted. Workers configured as manual or disabled by HCU_SCHEDULER_WORKERS are not scheduled")
+	grift.Add("schedule", func(c *grift.Context) error {
+		app := actions.App()
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		if err := app.Worker.Start(ctx); err != nil {
+			return err
+		}
+		workers.StartScheduler()
+		<-ctx.Done()
+		return app.Worker.Stop()
+	})
+
+})
@@ -4,9 +4,13 @@ import (
 	"log"
 
 	"github.com/hyeoncheon/honcheonui/actions"
+	"github.com/hyeoncheon/honcheonui/models"
 )
 
 func main() {
+	if err := models.CheckSecretKey(); err != nil {
+		log.Fatal(err)
+	}
 	app := actions.App()
 	if err := app.Serve(); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,228 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// conditions of alert rules
+const (
+	AlertPoweredOff       = "powered_off"
+	AlertDisconnected     = "disconnected"
+	AlertMissing          = "missing"
+	AlertAttributeChanged = "attribute_changed"
+)
+
+// AlertConditions is the list of all conditions of alert rules
+var AlertConditions = []string{
+	AlertPoweredOff,
+	AlertDisconnected,
+	AlertMissing,
+	AlertAttributeChanged,
+}
+
+// values of incidents raised by alert rules
+const (
+	AlertProvider = "honcheonui"
+	AlertType     = "alert"
+	AlertCategory = "Alert"
+)
+
+// AlertRule is a rule to raise an incident when a condition is detected on
+// a resource after resource sync. A rule is scoped to resources of a
+// service or resources tagged with a tag. Attribute is the name of the
+// attribute to watch for attribute_changed, or empty for any attribute.
+type AlertRule struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Name      string    `json:"name" db:"name"`
+	Condition string    `json:"condition" db:"alert_condition"`
+	Attribute string    `json:"attribute" db:"attribute"`
+	ServiceID uuid.UUID `json:"service_id" db:"service_id"`
+	TagID     uuid.UUID `json:"tag_id" db:"tag_id"`
+	Tag       *Tag      `json:"-" db:"-"`
+}
+
+// AlertRules is an array of alert rules
+type AlertRules []AlertRule
+
+// String returns the name of the rule
+func (a AlertRule) String() string {
+	return a.Name
+}
+
+// ResourceEvent is a condition detected on a resource by resource sync.
+type ResourceEvent struct {
+	ResourceID uuid.UUID
+	Condition  string
+	Attribute  string
+	OldValue   string
+	NewValue   string
+}
+
+// ResourceEvents is an array of resource events
+type ResourceEvents []ResourceEvent
+
+// String returns the description of the event
+func (e ResourceEvent) String() string {
+	switch e.Condition {
+	case AlertPoweredOff:
+		return "powered off"
+	case AlertDisconnected:
+		return "disconnected"
+	case AlertMissing:
+		return "disappeared from the provider"
+	case AlertAttributeChanged:
+		return fmt.Sprintf("attribute %v changed: %v -> %v", e.Attribute, e.OldValue, e.NewValue)
+	}
+	return e.Condition
+}
+
+// DetectResourceEvents returns conditions detected by comparing the stored
+// resource with the incoming resource and its attributes. The stored
+// resource should be loaded with its attributes. Attributes added for the
+// first time are not changes.
+func DetectResourceEvents(existing, res *Resource, attrs AttributeValues) ResourceEvents {
+	events := ResourceEvents{}
+	if existing.IsOn && !res.IsOn {
+		events = append(events, ResourceEvent{ResourceID: existing.ID, Condition: AlertPoweredOff})
+	}
+	if existing.IsConn && !res.IsConn {
+		events = append(events, ResourceEvent{ResourceID: existing.ID, Condition: AlertDisconnected})
+	}
+	for _, a := range existing.Attributes {
+		if value := attrs[a.Name].Value; value != a.Value {
+			events = append(events, ResourceEvent{
+				ResourceID: existing.ID,
+				Condition:  AlertAttributeChanged,
+				Attribute:  a.Name,
+				OldValue:   a.Value,
+				NewValue:   value,
+			})
+		}
+	}
+	return events
+}
+
+// Matches returns true if the rule applies to the event on a resource
+// which has the tags and belongs to the services.
+func (a AlertRule) Matches(e ResourceEvent, tagIDs, serviceIDs []uuid.UUID) bool {
+	if a.Condition != e.Condition {
+		return false
+	}
+	if a.Condition == AlertAttributeChanged && a.Attribute != "" && a.Attribute != e.Attribute {
+		return false
+	}
+	if a.ServiceID != uuid.Nil {
+		return overlaps([]uuid.UUID{a.ServiceID}, serviceIDs)
+	}
+	if a.TagID != uuid.Nil {
+		return overlaps([]uuid.UUID{a.TagID}, tagIDs)
+	}
+	return false
+}
+
+// Incident returns a new incident raised by the rule for the event on the
+// resource. Each event raises its own incident since events are detected
+// only on changes.
+func (a AlertRule) Incident(e ResourceEvent, r *Resource, now time.Time) *Incident {
+	groupID := r.GroupID
+	if groupID == "" {
+		groupID = AlertProvider
+	}
+	return &Incident{
+		Provider:   AlertProvider,
+		Type:       AlertType,
+		OriginalID: fmt.Sprintf("%v/%v/%v", a.ID, r.ID, now.UnixNano()),
+		GroupID:    groupID,
+		UserID:     AlertProvider,
+		Title:      r.Name + " " + e.String(),
+		Content:    fmt.Sprintf("Alert rule %v detected that resource %v (%v) was %v.", a.Name, r.Name, r.OriginalID, e),
+		Category:   AlertCategory,
+		IssuedBy:   AlertProvider,
+		IsOpen:     true,
+		IssuedAt:   now,
+		ModifiedAt: now,
+	}
+}
+
+// AllAlertRules returns all alert rules in creation order.
+func AllAlertRules() (*AlertRules, error) {
+	rules := &AlertRules{}
+	err := DB.Order("created_at").All(rules)
+	return rules, err
+}
+
+// AlertRules returns alert rules scoped to the service or its tags. The
+// service should be loaded with its tags.
+func (s Service) AlertRules() *AlertRules {
+	rules := &AlertRules{}
+	// placeholders are made by hand since pop expands "IN (?)" with all
+	// arguments of the statement.
+	stmt := "service_id = ?"
+	args := []interface{}{s.ID}
+	for _, t := range s.Tags {
+		stmt += " OR tag_id = ?"
+		args = append(args, t.ID)
+	}
+	query := DB.Where(stmt, args...).Order("created_at")
+	if err := query.All(rules); err != nil {
+		mlogger.Errorf("could not get alert rules of %v: %v", s.ID, err)
+	}
+	for i, r := range *rules {
+		for k, t := range s.Tags {
+			if r.TagID == t.ID {
+				(*rules)[i].Tag = &s.Tags[k]
+			}
+		}
+	}
+	return rules
+}
+
+// HasAlertRule returns true if the rule is scoped to the service or one of
+// its tags. The service should be loaded with its tags.
+func (s Service) HasAlertRule(rule *AlertRule) bool {
+	if rule.ServiceID != uuid.Nil {
+		return rule.ServiceID == s.ID
+	}
+	for _, t := range s.Tags {
+		if rule.TagID == t.ID {
+			return true
+		}
+	}
+	return false
+}
+
+//*** validators
+
+// Validate gets run every time you call a "pop.Validate*" method.
+func (a *AlertRule) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: a.Name, Name: "Name"},
+		&validators.StringInclusion{Field: a.Condition, Name: "Condition", List: AlertConditions},
+		&validators.FuncValidator{
+			Field:   a.Name,
+			Name:    "Scope",
+			Message: "rule %v should be scoped to either a service or a tag",
+			Fn: func() bool {
+				return (a.ServiceID == uuid.Nil) != (a.TagID == uuid.Nil)
+			},
+		},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (a *AlertRule) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (a *AlertRule) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
@@ -0,0 +1,42 @@
+package models_test
+
+import (
+	"github.com/gofrs/uuid"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+func (ms *ModelSuite) Test_Service_AlertRules() {
+	web := &models.Tag{Name: "web"}
+	db := &models.Tag{Name: "db"}
+	other := &models.Tag{Name: "other"}
+	for _, tag := range []*models.Tag{web, db, other} {
+		ms.NoError(ms.DB.Create(tag))
+	}
+	service := &models.Service{Name: "shop"}
+	ms.NoError(ms.DB.Create(service))
+
+	for _, rule := range []*models.AlertRule{
+		{Name: "service", Condition: models.AlertMissing, ServiceID: service.ID},
+		{Name: "web", Condition: models.AlertMissing, TagID: web.ID},
+		{Name: "db", Condition: models.AlertMissing, TagID: db.ID},
+		{Name: "other", Condition: models.AlertMissing, TagID: other.ID},
+		{Name: "another", Condition: models.AlertMissing, ServiceID: uuid.Must(uuid.NewV4())},
+	} {
+		ms.NoError(ms.DB.Create(rule))
+	}
+
+	// with a tag, "IN (?)" could be expanded with the service id too
+	service.Tags = models.Tags{*web}
+	ms.Len(*service.AlertRules(), 2)
+
+	service.Tags = models.Tags{*web, *db}
+	rules := service.AlertRules()
+	ms.Len(*rules, 3)
+	for _, rule := range *rules {
+		ms.True(service.HasAlertRule(&rule), rule.Name)
+	}
+
+	service.Tags = models.Tags{}
+	ms.Len(*service.AlertRules(), 1)
+}
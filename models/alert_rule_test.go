@@ -0,0 +1,91 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DetectResourceEvents(t *testing.T) {
+	r := require.New(t)
+
+	existing := &Resource{
+		ID:     uuid.Must(uuid.NewV4()),
+		IsOn:   true,
+		IsConn: true,
+		Attributes: Attributes{
+			{Name: "os", Value: "Ubuntu 18.04"},
+			{Name: "cpu", Value: "4"},
+		},
+	}
+	res := &Resource{IsOn: false, IsConn: true}
+	events := DetectResourceEvents(existing, res, AttributeValues{
+		"os":     StringValue("Ubuntu 20.04"),
+		"cpu":    IntegerValue(4),
+		"memory": IntegerValue(8192),
+	})
+	r.Len(events, 2)
+	r.Equal(AlertPoweredOff, events[0].Condition)
+	r.Equal(existing.ID, events[0].ResourceID)
+	r.Equal(AlertAttributeChanged, events[1].Condition)
+	r.Equal("os", events[1].Attribute)
+	r.Equal("Ubuntu 18.04", events[1].OldValue)
+	r.Equal("Ubuntu 20.04", events[1].NewValue)
+
+	existing.IsOn = false
+	existing.Attributes = Attributes{}
+	r.Len(DetectResourceEvents(existing, res, nil), 0)
+}
+
+func Test_AlertRule_Matches(t *testing.T) {
+	r := require.New(t)
+
+	serviceID := uuid.Must(uuid.NewV4())
+	tagID := uuid.Must(uuid.NewV4())
+	off := ResourceEvent{Condition: AlertPoweredOff}
+	os := ResourceEvent{Condition: AlertAttributeChanged, Attribute: "os"}
+
+	rule := AlertRule{Condition: AlertPoweredOff, ServiceID: serviceID}
+	r.True(rule.Matches(off, nil, []uuid.UUID{serviceID}))
+	r.False(rule.Matches(off, []uuid.UUID{tagID}, nil))
+	r.False(rule.Matches(os, nil, []uuid.UUID{serviceID}))
+
+	rule = AlertRule{Condition: AlertAttributeChanged, TagID: tagID}
+	r.True(rule.Matches(os, []uuid.UUID{tagID}, nil))
+	rule.Attribute = "cpu"
+	r.False(rule.Matches(os, []uuid.UUID{tagID}, nil))
+
+	rule = AlertRule{Condition: AlertPoweredOff}
+	r.False(rule.Matches(off, []uuid.UUID{tagID}, []uuid.UUID{serviceID}))
+}
+
+func Test_AlertRule_Incident(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Now()
+	rule := AlertRule{ID: uuid.Must(uuid.NewV4()), Name: "web down", Condition: AlertMissing}
+	res := &Resource{ID: uuid.Must(uuid.NewV4()), Name: "web-01", OriginalID: "1234"}
+	inci := rule.Incident(ResourceEvent{ResourceID: res.ID, Condition: AlertMissing}, res, now)
+	r.Equal(AlertProvider, inci.Provider)
+	r.Equal(AlertType, inci.Type)
+	r.Equal(AlertProvider, inci.GroupID)
+	r.Equal("web-01 disappeared from the provider", inci.Title)
+	r.True(strings.HasPrefix(inci.OriginalID, rule.ID.String()+"/"+res.ID.String()+"/"))
+	r.True(inci.IsOpen)
+	r.Equal(now, inci.IssuedAt)
+}
+
+func Test_Service_HasAlertRule(t *testing.T) {
+	r := require.New(t)
+
+	tag := Tag{ID: uuid.Must(uuid.NewV4()), Name: "web"}
+	service := Service{ID: uuid.Must(uuid.NewV4()), Tags: Tags{tag}}
+	r.True(service.HasAlertRule(&AlertRule{ServiceID: service.ID}))
+	r.True(service.HasAlertRule(&AlertRule{TagID: tag.ID}))
+	r.False(service.HasAlertRule(&AlertRule{ServiceID: uuid.Must(uuid.NewV4())}))
+	r.False(service.HasAlertRule(&AlertRule{TagID: uuid.Must(uuid.NewV4())}))
+	r.False(service.HasAlertRule(&AlertRule{}))
+}
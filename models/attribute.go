@@ -17,10 +17,33 @@ type Attribute struct {
 	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
 	Name       string    `json:"name" db:"name"`
 	Value      string    `json:"value" db:"value"`
+	Type       string    `json:"type" db:"type"`
+	Number     float64   `json:"number" db:"number"`
 	ResourceID uuid.UUID `json:"resource_id" db:"resource_id"`
 	Resource   Resource  `belongs_to:"resource"`
 }
 
+// AttributeChange is a history of attribute value. Added attributes have
+// empty old value and removed ones have empty new value.
+type AttributeChange struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	ResourceID uuid.UUID `json:"resource_id" db:"resource_id"`
+	SyncRunID  uuid.UUID `json:"sync_run_id" db:"sync_run_id"`
+	Name       string    `json:"name" db:"name"`
+	OldValue   string    `json:"old_value" db:"old_value"`
+	NewValue   string    `json:"new_value" db:"new_value"`
+}
+
+// AttributeChanges is an array of attribute changes
+type AttributeChanges []AttributeChange
+
+// HasSyncRun returns true if the change was made by a sync run.
+func (a AttributeChange) HasSyncRun() bool {
+	return a.SyncRunID != uuid.Nil
+}
+
 // String returns name:value formmatted string
 func (a Attribute) String() string {
 	return a.Name + ":" + a.Value
@@ -35,6 +58,29 @@ func (a Attribute) JSON() string {
 // Attributes is an array of attributes
 type Attributes []Attribute
 
+//*** typed value
+
+// SetValue sets the value with its type and numeric representation.
+func (a *Attribute) SetValue(value AttributeValue) {
+	a.Value = value.Value
+	a.Type = value.Type
+	a.Number = value.Number()
+}
+
+// IsNumeric returns true if the attribute could be compared as a number.
+func (a Attribute) IsNumeric() bool {
+	return a.Type == AttrInteger
+}
+
+// Formatted returns the value formatted for its type. Integers are
+// grouped by thousands and other values are returned as they are.
+func (a Attribute) Formatted() string {
+	if a.Type == AttrInteger {
+		return groupThousands(a.Value)
+	}
+	return a.Value
+}
+
 //*** validators
 
 // Validate gets run every time you call a "pop.Validate*" method.
@@ -0,0 +1,83 @@
+package models_test
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+// historyOf returns attribute changes of the resource in time order.
+func (ms *ModelSuite) historyOf(r *models.Resource) models.AttributeChanges {
+	changes := models.AttributeChanges{}
+	ms.NoError(ms.DB.Where("resource_id = ?", r.ID).Order("created_at").All(&changes))
+	return changes
+}
+
+func (ms *ModelSuite) Test_Resource_SetAttribute_History() {
+	r := ms.resource("web-01", models.ResourceActive, time.Hour)
+	runID := uuid.Must(uuid.NewV4())
+
+	ms.NoError(r.SetAttribute(ms.DB, runID, "memory", models.IntegerValue(4096)))
+	ms.NoError(r.SetAttribute(ms.DB, runID, "memory", models.IntegerValue(4096)))
+	history := ms.historyOf(r)
+	ms.Len(history, 1, "unchanged value is not recorded")
+	ms.Equal("memory", history[0].Name)
+	ms.Equal("", history[0].OldValue)
+	ms.Equal("4096", history[0].NewValue)
+	ms.Equal(runID, history[0].SyncRunID)
+
+	// manual changes have no sync run
+	ms.NoError(r.SetAttribute(ms.DB, uuid.Nil, "memory", models.IntegerValue(8192)))
+	history = ms.historyOf(r)
+	ms.Len(history, 2)
+	ms.Equal("4096", history[1].OldValue)
+	ms.Equal("8192", history[1].NewValue)
+	ms.False(history[1].HasSyncRun())
+
+	// type only changes update the attribute without history
+	ms.NoError(r.SetAttribute(ms.DB, runID, "memory", models.StringValue("8192")))
+	ms.Len(ms.historyOf(r), 2)
+	attr := &models.Attribute{}
+	ms.NoError(ms.DB.Where("resource_id = ? AND name = ?", r.ID, "memory").First(attr))
+	ms.Equal(models.AttrString, attr.Type)
+
+	ms.NoError(r.UnsetAttribute(ms.DB, runID, attr))
+	history = ms.historyOf(r)
+	ms.Len(history, 3)
+	ms.Equal("8192", history[2].OldValue)
+	ms.Equal("", history[2].NewValue)
+	count, err := ms.DB.Where("resource_id = ?", r.ID).Count(&models.Attribute{})
+	ms.NoError(err)
+	ms.Equal(0, count)
+}
+
+func (ms *ModelSuite) Test_Resource_SyncAttributes_History() {
+	r := ms.resource("web-01", models.ResourceActive, time.Hour)
+	first := uuid.Must(uuid.NewV4())
+	ms.NoError(r.SyncAttributes(ms.DB, first, models.AttributeValues{
+		"os":  models.StringValue("Ubuntu 18.04"),
+		"cpu": models.IntegerValue(2),
+	}))
+	ms.Len(ms.historyOf(r), 2)
+
+	second := uuid.Must(uuid.NewV4())
+	ms.NoError(r.SyncAttributes(ms.DB, second, models.AttributeValues{
+		"os":     models.StringValue("Ubuntu 20.04"),
+		"memory": models.IntegerValue(4096),
+	}))
+	changes := map[string]models.AttributeChange{}
+	for _, c := range ms.historyOf(r) {
+		if c.SyncRunID == second {
+			changes[c.Name] = c
+		}
+	}
+	ms.Len(changes, 3)
+	ms.Equal("Ubuntu 18.04", changes["os"].OldValue)
+	ms.Equal("Ubuntu 20.04", changes["os"].NewValue)
+	ms.Equal("2", changes["cpu"].OldValue)
+	ms.Equal("", changes["cpu"].NewValue)
+	ms.Equal("", changes["memory"].OldValue)
+	ms.Equal("4096", changes["memory"].NewValue)
+}
@@ -1,7 +1,64 @@
 package models
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+)
 
 func Test_Attribute(t *testing.T) {
 	t.Fatal("This test needs to be implemented!")
 }
+
+func Test_AttributeValue_Number(t *testing.T) {
+	r := require.New(t)
+	r.Equal(4096.0, IntegerValue(4096).Number())
+	r.Equal(0.0, StringValue("4096").Number())
+	r.Equal(-12.0, IntegerValue(-12).Number())
+}
+
+func Test_Attribute_Formatted(t *testing.T) {
+	r := require.New(t)
+	a := &Attribute{Name: "memory"}
+	a.SetValue(IntegerValue(1048576))
+	r.True(a.IsNumeric())
+	r.Equal(1048576.0, a.Number)
+	r.Equal("1,048,576", a.Formatted())
+
+	a.SetValue(IntegerValue(-1234))
+	r.Equal("-1,234", a.Formatted())
+	a.SetValue(StringValue("1.10"))
+	r.Equal("1.10", a.Formatted())
+	a.SetValue(StringValue("20.04"))
+	r.Equal(AttrString, a.Type)
+	r.False(a.IsNumeric())
+	r.Equal("20.04", a.Formatted())
+	a.SetValue(StringValue("1048576"))
+	r.Equal("1048576", a.Formatted())
+}
+
+func Test_ParseAttributeFilter(t *testing.T) {
+	r := require.New(t)
+
+	f, err := ParseAttributeFilter("cpu >= 4")
+	r.NoError(err)
+	r.Equal(AttributeFilter{Name: "cpu", Op: ">=", Value: "4"}, *f)
+	r.Equal("cpu>=4", f.String())
+
+	f, err = ParseAttributeFilter("os=Ubuntu 20.04")
+	r.NoError(err)
+	r.Equal("Ubuntu 20.04", f.Value)
+
+	for _, expr := range []string{"cpu", "cpu>=", "os>Ubuntu", ">=4"} {
+		_, err = ParseAttributeFilter(expr)
+		r.Error(err, expr)
+	}
+}
+
+func Test_AttributeChange_HasSyncRun(t *testing.T) {
+	r := require.New(t)
+
+	r.False(AttributeChange{Name: "memory"}.HasSyncRun())
+	r.True(AttributeChange{Name: "memory", SyncRunID: uuid.Must(uuid.NewV4())}.HasSyncRun())
+}
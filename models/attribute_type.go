@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gobuffalo/pop/v5"
+)
+
+// types of attributes given by providers
+const (
+	AttrString  = "string"
+	AttrInteger = "integer"
+)
+
+// AttributeValue is a value of attribute with its type given by the
+// provider. Values are stored as they are and the type is never guessed
+// from the value.
+type AttributeValue struct {
+	Value string
+	Type  string
+}
+
+// AttributeValues is a map of attribute values by their names
+type AttributeValues map[string]AttributeValue
+
+// StringValue returns a string attribute value.
+func StringValue(value string) AttributeValue {
+	return AttributeValue{Value: value, Type: AttrString}
+}
+
+// IntegerValue returns an integer attribute value.
+func IntegerValue(value int) AttributeValue {
+	return AttributeValue{Value: strconv.Itoa(value), Type: AttrInteger}
+}
+
+// Number returns numeric representation of the value for comparison.
+// Strings are 0.
+func (v AttributeValue) Number() float64 {
+	if v.Type != AttrInteger {
+		return 0
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v.Value), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// groupThousands inserts commas into string of integer.
+func groupThousands(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return sign + s
+}
+
+//*** attribute filter
+
+// AttributeFilter is a condition on a resource attribute such as
+// "cpu>=4" or "os=Ubuntu".
+type AttributeFilter struct {
+	Name  string
+	Op    string
+	Value string
+}
+
+var attrFilterPattern = regexp.MustCompile(`^\s*([\w.-]+)\s*(>=|<=|!=|=|>|<)\s*(.*?)\s*$`)
+
+// ParseAttributeFilter parses filter expression "name op value".
+func ParseAttributeFilter(expr string) (*AttributeFilter, error) {
+	m := attrFilterPattern.FindStringSubmatch(expr)
+	if m == nil || m[3] == "" {
+		return nil, errors.New("invalid attribute filter: " + expr)
+	}
+	f := &AttributeFilter{Name: m[1], Op: m[2], Value: m[3]}
+	if f.Op != "=" && f.Op != "!=" {
+		if _, err := strconv.ParseFloat(f.Value, 64); err != nil {
+			return nil, errors.New("could not compare with non-numeric value: " + expr)
+		}
+	}
+	return f, nil
+}
+
+// String returns the filter expression
+func (f AttributeFilter) String() string {
+	return f.Name + f.Op + f.Value
+}
+
+// Apply adds the condition to the query of resources. Equality is checked
+// on the value as it is stored, and ordering on the number of integer
+// attributes.
+func (f AttributeFilter) Apply(q *pop.Query) *pop.Query {
+	cond := "attributes.value " + f.Op + " ?"
+	var arg interface{} = f.Value
+	if f.Op != "=" && f.Op != "!=" {
+		number, _ := strconv.ParseFloat(f.Value, 64)
+		cond = "attributes.type = '" + AttrInteger + "' AND attributes.number " + f.Op + " ?"
+		arg = number
+	}
+	return q.Where("EXISTS (SELECT 1 FROM attributes WHERE attributes.resource_id = resources.id"+
+		" AND attributes.name = ? AND "+cond+")", f.Name, arg)
+}
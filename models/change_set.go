@@ -0,0 +1,126 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// kinds of resource changes
+const (
+	ChangeAdded     = "added"
+	ChangeModified  = "modified"
+	ChangeRemoved   = "removed"
+	ChangeUnchanged = "unchanged"
+)
+
+// ChangeSet is a result of a resource sync run for a provider. It keeps
+// counts of each kind of changes and the changes except unchanged ones.
+// Events detected on the run are kept in memory for alert rules.
+type ChangeSet struct {
+	ID         uuid.UUID       `json:"id" db:"id"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at" db:"updated_at"`
+	ProviderID uuid.UUID       `json:"provider_id" db:"provider_id"`
+	Added      int             `json:"added" db:"added"`
+	Modified   int             `json:"modified" db:"modified"`
+	Removed    int             `json:"removed" db:"removed"`
+	Unchanged  int             `json:"unchanged" db:"unchanged"`
+	Provider   Provider        `json:"-" belongs_to:"provider"`
+	Changes    ResourceChanges `json:"changes" has_many:"resource_changes" order_by:"kind, name"`
+	Events     ResourceEvents  `json:"-" db:"-"`
+}
+
+// ResourceChange is a change of a resource on a sync run.
+type ResourceChange struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ChangeSetID uuid.UUID `json:"change_set_id" db:"change_set_id"`
+	ResourceID  uuid.UUID `json:"resource_id" db:"resource_id"`
+	Name        string    `json:"name" db:"name"`
+	Kind        string    `json:"kind" db:"kind"`
+	Details     string    `json:"details" db:"details"`
+}
+
+// String returns summary of the change set
+func (c ChangeSet) String() string {
+	return c.CreatedAt.Format(time.RFC3339) + " " + c.Provider.String()
+}
+
+// IsEmpty returns true if nothing was changed on the sync run.
+func (c ChangeSet) IsEmpty() bool {
+	return c.Added == 0 && c.Modified == 0 && c.Removed == 0
+}
+
+// ChangeSets is an array of change sets
+type ChangeSets []ChangeSet
+
+// ResourceChanges is an array of resource changes
+type ResourceChanges []ResourceChange
+
+// NewChangeSet returns new empty change set for the provider.
+func NewChangeSet(providerID uuid.UUID) *ChangeSet {
+	return &ChangeSet{ProviderID: providerID}
+}
+
+// Add records a change of the resource. Unchanged resources are counted
+// only.
+func (c *ChangeSet) Add(kind string, r *Resource, details ...string) {
+	switch kind {
+	case ChangeAdded:
+		c.Added++
+	case ChangeModified:
+		c.Modified++
+	case ChangeRemoved:
+		c.Removed++
+	case ChangeUnchanged:
+		c.Unchanged++
+		return
+	}
+	c.Changes = append(c.Changes, ResourceChange{
+		ResourceID: r.ID,
+		Name:       r.Name,
+		Kind:       kind,
+		Details:    strings.Join(details, "\n"),
+	})
+}
+
+//*** common database functions and methods
+
+// Save stores the change set and its changes
+func (c *ChangeSet) Save(tx *pop.Connection) error {
+	if err := tx.Create(c); err != nil {
+		return err
+	}
+	for i := range c.Changes {
+		c.Changes[i].ChangeSetID = c.ID
+		if err := tx.Create(&c.Changes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//*** validators
+
+// Validate gets run every time you call a "pop.Validate*" method.
+func (c *ChangeSet) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: c.ProviderID, Name: "ProviderID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (c *ChangeSet) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (c *ChangeSet) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ChangeSet_Add(t *testing.T) {
+	r := require.New(t)
+	cs := NewChangeSet(uuid.Must(uuid.NewV4()))
+	res := &Resource{Name: "web-01"}
+
+	cs.Add(ChangeAdded, res)
+	cs.Add(ChangeModified, res, "name: web -> web-01", "is_on: false -> true")
+	cs.Add(ChangeUnchanged, res)
+	cs.Add(ChangeUnchanged, res)
+	cs.Add(ChangeRemoved, res)
+
+	r.Equal(1, cs.Added)
+	r.Equal(1, cs.Modified)
+	r.Equal(1, cs.Removed)
+	r.Equal(2, cs.Unchanged)
+	r.Len(cs.Changes, 3)
+	r.Equal("name: web -> web-01\nis_on: false -> true", cs.Changes[1].Details)
+	r.False(cs.IsEmpty())
+	r.True(NewChangeSet(cs.ProviderID).IsEmpty())
+}
+
+func Test_Resource_Diff(t *testing.T) {
+	r := require.New(t)
+	now := time.Now()
+	stored := &Resource{
+		Name:               "web-01",
+		IsOn:               true,
+		ResourceModifiedAt: now.Truncate(time.Second),
+		Tags:               Tags{{Name: "web"}, {Name: "production"}},
+		Attributes:         Attributes{{Name: "os", Value: "Ubuntu", Type: AttrString}},
+	}
+	incoming := &Resource{Name: "web-01", IsOn: true, ResourceModifiedAt: now}
+
+	diff := stored.Diff(incoming, []string{"production", " web"}, AttributeValues{"os": StringValue("Ubuntu")})
+	r.True(diff.IsEmpty())
+
+	incoming.IsOn = false
+	diff = stored.Diff(incoming, []string{"web"}, AttributeValues{"os": StringValue("CentOS"), "cpu": IntegerValue(2)})
+	r.False(diff.IsEmpty())
+	r.Equal([]string{"is_on"}, diff.Fields)
+	r.True(diff.Tags)
+	r.True(diff.Attributes)
+	r.Contains(diff.Details, "is_on: true -> false")
+	r.Contains(diff.Details, "attribute os: Ubuntu -> CentOS")
+	r.Contains(diff.Details, "attribute cpu: added 2")
+
+	stored.Attributes = append(stored.Attributes, Attribute{Name: "os", Value: "Ubuntu", Type: AttrString})
+	diff = stored.Diff(stored, []string{"web", "production"}, AttributeValues{"os": StringValue("Ubuntu")})
+	r.Empty(diff.Fields)
+	r.True(diff.Attributes, "duplicated attributes should be cleaned")
+}
@@ -0,0 +1,285 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// CorrelationRule is a rule to group incidents into a parent incident.
+// Incidents issued within the window are grouped if they match all
+// criteria of the rule. A rule with a provider applies only to incidents
+// of the provider.
+type CorrelationRule struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	Name       string    `json:"name" db:"name"`
+	Provider   string    `json:"provider" db:"provider"`
+	Window     string    `json:"window" db:"time_window"`
+	ByResource bool      `json:"by_resource" db:"by_resource"`
+	ByCode     bool      `json:"by_code" db:"by_code"`
+	ByCategory bool      `json:"by_category" db:"by_category"`
+}
+
+// CorrelationRules is an array of correlation rules
+type CorrelationRules []CorrelationRule
+
+// String returns the name of the rule
+func (r CorrelationRule) String() string {
+	return r.Name
+}
+
+// WindowDuration returns the window of the rule, or zero if invalid.
+func (r CorrelationRule) WindowDuration() time.Duration {
+	d, err := time.ParseDuration(r.Window)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Matches returns true if the incidents are grouped by the rule. Linked
+// resources of the incidents are given as ids.
+func (r CorrelationRule) Matches(a, b *Incident, resA, resB []uuid.UUID) bool {
+	if !r.ByResource && !r.ByCode && !r.ByCategory {
+		return false
+	}
+	if r.Provider != "" && (a.Provider != r.Provider || b.Provider != r.Provider) {
+		return false
+	}
+	diff := a.IssuedAt.Sub(b.IssuedAt)
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > r.WindowDuration() {
+		return false
+	}
+	if r.ByCode && (a.Code == 0 || a.Code != b.Code) {
+		return false
+	}
+	if r.ByCategory && (a.Category == "" || a.Category != b.Category) {
+		return false
+	}
+	if r.ByResource && !overlaps(resA, resB) {
+		return false
+	}
+	return true
+}
+
+// overlaps returns true if the lists have a common id.
+func overlaps(a, b []uuid.UUID) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllCorrelationRules returns all correlation rules in creation order.
+func AllCorrelationRules() (*CorrelationRules, error) {
+	rules := &CorrelationRules{}
+	err := DB.Order("created_at").All(rules)
+	return rules, err
+}
+
+// LinkedResourceIDs returns ids of resources linked with the incident.
+func (i Incident) LinkedResourceIDs() []uuid.UUID {
+	maps := &[]IncidentsResources{}
+	if err := DB.Where("incident_id = ?", i.ID).All(maps); err != nil {
+		mlogger.Errorf("could not get linked resources of %v: %v", i.ID, err)
+	}
+	ids := []uuid.UUID{}
+	for _, m := range *maps {
+		ids = append(ids, m.ResourceID)
+	}
+	return ids
+}
+
+// Correlate groups the incident into a parent incident by the rules. The
+// parent is the earliest top-level incident issued before the incident
+// and matched by the first matching rule. It returns the parent, or nil if
+// the incident is not grouped. Incidents already grouped or having
+// children are not correlated again.
+func (i *Incident) Correlate(rules CorrelationRules) (*Incident, error) {
+	if i.HasParent() || len(rules) == 0 {
+		return nil, nil
+	}
+	if n, err := DB.Where("parent_id = ?", i.ID).Count(&Incident{}); err != nil || n > 0 {
+		return nil, err
+	}
+
+	var window time.Duration
+	for _, r := range rules {
+		if d := r.WindowDuration(); d > window {
+			window = d
+		}
+	}
+	candidates := &Incidents{}
+	err := DB.Where("id != ? AND parent_id = ? AND issued_at BETWEEN ? AND ?",
+		i.ID, uuid.Nil, i.IssuedAt.Add(-window), i.IssuedAt).
+		Order("issued_at").All(candidates)
+	if err != nil || len(*candidates) == 0 {
+		return nil, err
+	}
+
+	resources := i.LinkedResourceIDs()
+	linked := map[uuid.UUID][]uuid.UUID{}
+	for _, r := range rules {
+		for k := range *candidates {
+			c := &(*candidates)[k]
+			if _, ok := linked[c.ID]; !ok && r.ByResource {
+				linked[c.ID] = c.LinkedResourceIDs()
+			}
+			if !r.Matches(i, c, resources, linked[c.ID]) {
+				continue
+			}
+			grouped, err := i.groupInto(c, r)
+			if err != nil || !grouped {
+				return nil, err
+			}
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+// groupInto sets the parent of the incident and records it on both of
+// them. The incident is grouped only if it is still top-level without
+// children and the parent is still top-level, since other incidents could
+// be correlated at the same time. Both of them are locked while they are
+// checked. It returns false if it was not grouped.
+func (i *Incident) groupInto(c *Incident, r CorrelationRule) (bool, error) {
+	grouped := false
+	err := DB.Transaction(func(tx *pop.Connection) error {
+		locked := &Incidents{}
+		err := tx.RawQuery("SELECT * FROM incidents WHERE id IN (?, ?) ORDER BY id FOR UPDATE", i.ID, c.ID).All(locked)
+		if err != nil {
+			return err
+		}
+		for _, l := range *locked {
+			if l.HasParent() {
+				return nil
+			}
+		}
+		children := &Incidents{}
+		if err := tx.RawQuery("SELECT * FROM incidents WHERE parent_id = ? FOR UPDATE", i.ID).All(children); err != nil {
+			return err
+		}
+		if len(*locked) != 2 || len(*children) > 0 {
+			return nil
+		}
+
+		n, err := tx.RawQuery("UPDATE incidents SET parent_id = ? WHERE id = ? AND parent_id = ?",
+			c.ID, i.ID, uuid.Nil).ExecWithCount()
+		if err != nil || n == 0 {
+			return err
+		}
+		i.ParentID = c.ID
+		i.addSystemEvent(tx, EventCorrelation, "grouped into "+c.Title+" by rule "+r.Name)
+		c.addSystemEvent(tx, EventCorrelation, "grouped "+i.Title+" by rule "+r.Name)
+		grouped = true
+		return nil
+	})
+	if err != nil {
+		i.ParentID = uuid.Nil
+		return false, err
+	}
+	return grouped, nil
+}
+
+// HasParent returns true if the incident is grouped into a parent.
+func (i Incident) HasParent() bool {
+	return i.ParentID != uuid.Nil
+}
+
+// IncidentGroup is a top-level incident with incidents grouped into it.
+type IncidentGroup struct {
+	Parent   Incident  `json:"parent"`
+	Children Incidents `json:"children"`
+}
+
+// GroupIncidents groups incidents by their parents. Parents not in the
+// list are loaded, and groups are sorted by the latest issued first.
+func GroupIncidents(incidents Incidents) []IncidentGroup {
+	groups := []IncidentGroup{}
+	index := map[uuid.UUID]int{}
+	seen := map[uuid.UUID]bool{}
+
+	group := func(parent Incident) int {
+		if n, ok := index[parent.ID]; ok {
+			return n
+		}
+		index[parent.ID] = len(groups)
+		groups = append(groups, IncidentGroup{Parent: parent, Children: Incidents{}})
+		return index[parent.ID]
+	}
+	for _, i := range incidents {
+		if !i.HasParent() {
+			groups[group(i)].Parent = i
+		}
+	}
+	for _, i := range incidents {
+		if !i.HasParent() || seen[i.ID] {
+			continue
+		}
+		seen[i.ID] = true
+		if _, ok := index[i.ParentID]; !ok {
+			parent := Incident{}
+			if err := DB.Find(&parent, i.ParentID); err != nil {
+				mlogger.Errorf("could not find parent %v of %v: %v", i.ParentID, i.ID, err)
+				groups[group(i)].Parent = i
+				continue
+			}
+			group(parent)
+		}
+		n := index[i.ParentID]
+		groups[n].Children = append(groups[n].Children, i)
+	}
+	sort.SliceStable(groups, func(a, b int) bool {
+		return groups[a].Parent.IssuedAt.After(groups[b].Parent.IssuedAt)
+	})
+	return groups
+}
+
+//*** validators
+
+// Validate gets run every time you call a "pop.Validate*" method.
+func (r *CorrelationRule) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: r.Name, Name: "Name"},
+		&validators.FuncValidator{
+			Field:   r.Window,
+			Name:    "Window",
+			Message: "%v is not a valid duration",
+			Fn: func() bool {
+				return r.WindowDuration() > 0
+			},
+		},
+		&validators.FuncValidator{
+			Field:   r.Name,
+			Name:    "Criteria",
+			Message: "rule %v has no criteria",
+			Fn: func() bool {
+				return r.ByResource || r.ByCode || r.ByCategory
+			},
+		},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (r *CorrelationRule) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (r *CorrelationRule) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
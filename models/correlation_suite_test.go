@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+func (ms *ModelSuite) Test_Incident_Correlate() {
+	rules := models.CorrelationRules{{Name: "same code", Window: "10m", ByCode: true}}
+	now := time.Now().Truncate(time.Second)
+	incident := func(id string, issuedAt time.Time) *models.Incident {
+		i := &models.Incident{
+			Provider: "fake", Type: "alert", OriginalID: id, GroupID: "100", UserID: "1001",
+			Title: id, Content: id, Category: "storage", Code: 500,
+			IssuedBy: "fake", IsOpen: true, IssuedAt: issuedAt, ModifiedAt: issuedAt,
+		}
+		_, _, err := i.Upsert()
+		ms.NoError(err)
+		return i
+	}
+
+	// incidents issued later are never parents
+	later := incident("later", now.Add(5*time.Minute))
+	first := incident("first", now)
+	parent, err := first.Correlate(rules)
+	ms.NoError(err)
+	ms.Nil(parent)
+
+	parent, err = later.Correlate(rules)
+	ms.NoError(err)
+	ms.NotNil(parent)
+	ms.Equal(first.ID, parent.ID)
+	ms.Equal(first.ID, later.ParentID)
+
+	// incidents having children are not grouped again
+	earlier := incident("earlier", now.Add(-time.Minute))
+	parent, err = first.Correlate(rules)
+	ms.NoError(err)
+	ms.Nil(parent)
+	stored := &models.Incident{}
+	ms.NoError(ms.DB.Find(stored, first.ID))
+	ms.Equal(uuid.Nil, stored.ParentID)
+
+	// a child could not be a parent
+	ms.NoError(ms.DB.RawQuery("UPDATE incidents SET parent_id = ? WHERE id = ?", first.ID, earlier.ID).Exec())
+	next := incident("next", now.Add(time.Minute))
+	parent, err = next.Correlate(rules)
+	ms.NoError(err)
+	ms.NotNil(parent)
+	ms.Equal(first.ID, parent.ID)
+}
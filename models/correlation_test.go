@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CorrelationRule_Matches(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Now()
+	res1 := uuid.Must(uuid.NewV4())
+	res2 := uuid.Must(uuid.NewV4())
+	a := &Incident{Provider: "softlayer", Code: 100, Category: "Maintenance", IssuedAt: now}
+	b := &Incident{Provider: "softlayer", Code: 100, Category: "Outage", IssuedAt: now.Add(30 * time.Minute)}
+
+	rule := CorrelationRule{Name: "same resource", Window: "1h", ByResource: true}
+	r.True(rule.Matches(a, b, []uuid.UUID{res1, res2}, []uuid.UUID{res2}))
+	r.True(rule.Matches(b, a, []uuid.UUID{res2}, []uuid.UUID{res1, res2}))
+	r.False(rule.Matches(a, b, []uuid.UUID{res1}, []uuid.UUID{res2}))
+
+	rule.Window = "10m"
+	r.False(rule.Matches(a, b, []uuid.UUID{res1}, []uuid.UUID{res1}))
+
+	rule = CorrelationRule{Name: "same code", Window: "1h", ByCode: true}
+	r.True(rule.Matches(a, b, nil, nil))
+	rule.ByCategory = true
+	r.False(rule.Matches(a, b, nil, nil))
+
+	rule = CorrelationRule{Name: "other provider", Window: "1h", ByCode: true, Provider: "aws"}
+	r.False(rule.Matches(a, b, nil, nil))
+
+	rule = CorrelationRule{Name: "no criteria", Window: "1h"}
+	r.False(rule.Matches(a, b, nil, nil))
+
+	rule = CorrelationRule{Name: "invalid window", Window: "soon", ByCode: true}
+	r.Equal(time.Duration(0), rule.WindowDuration())
+	r.False(rule.Matches(a, b, nil, nil))
+}
+
+func Test_GroupIncidents(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Now()
+	p1 := Incident{ID: uuid.Must(uuid.NewV4()), Title: "p1", IssuedAt: now.Add(-2 * time.Hour)}
+	p2 := Incident{ID: uuid.Must(uuid.NewV4()), Title: "p2", IssuedAt: now.Add(-1 * time.Hour)}
+	c1 := Incident{ID: uuid.Must(uuid.NewV4()), Title: "c1", ParentID: p1.ID, IssuedAt: now}
+
+	groups := GroupIncidents(Incidents{c1, p1, p2, c1})
+	r.Len(groups, 2)
+	r.Equal("p2", groups[0].Parent.Title)
+	r.Len(groups[0].Children, 0)
+	r.Equal("p1", groups[1].Parent.Title)
+	r.Len(groups[1].Children, 1)
+	r.Equal("c1", groups[1].Children[0].Title)
+}
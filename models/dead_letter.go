@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// DeadLetter is a job failed permanently after all retry attempts. It keeps
+// the arguments of the job so administrators could requeue it.
+type DeadLetter struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Worker    string    `json:"worker" db:"worker"`
+	Args      string    `json:"args" db:"args"`
+	Attempts  int       `json:"attempts" db:"attempts"`
+	Error     string    `json:"error" db:"error"`
+}
+
+// DeadLetters is an array of dead letters
+type DeadLetters []DeadLetter
+
+// String returns the worker name of the dead letter
+func (d DeadLetter) String() string {
+	return d.Worker
+}
+
+// NewDeadLetter returns a dead letter for the failed job.
+func NewDeadLetter(worker string, args map[string]interface{}, attempts int, err error) *DeadLetter {
+	d := &DeadLetter{
+		Worker:   worker,
+		Args:     "{}",
+		Attempts: attempts,
+		Error:    "",
+	}
+	if jb, e := json.Marshal(args); e == nil && args != nil {
+		d.Args = string(jb)
+	}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	return d
+}
+
+// ArgsMap returns the arguments of the job as a map.
+func (d DeadLetter) ArgsMap() (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	if d.Args == "" {
+		return args, nil
+	}
+	err := json.Unmarshal([]byte(d.Args), &args)
+	return args, err
+}
+
+// RecentDeadLetters returns recent dead letters of all workers.
+func RecentDeadLetters(limit int) *DeadLetters {
+	letters := &DeadLetters{}
+	if err := DB.Order("created_at desc").Limit(limit).All(letters); err != nil {
+		mlogger.Errorf("could not get dead letters: %v", err)
+	}
+	return letters
+}
+
+//*** validators
+
+// Validate gets run every time you call a "pop.Validate*" method.
+func (d *DeadLetter) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: d.Worker, Name: "Worker"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (d *DeadLetter) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (d *DeadLetter) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DeadLetter_Args(t *testing.T) {
+	r := require.New(t)
+
+	d := NewDeadLetter("worker.ResourceSync", map[string]interface{}{
+		"provider_id": "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+	}, 4, errors.New("plugin call timed out"))
+	r.Equal(4, d.Attempts)
+	r.Equal("plugin call timed out", d.Error)
+
+	args, err := d.ArgsMap()
+	r.NoError(err)
+	r.Equal("6ba7b810-9dad-11d1-80b4-00c04fd430c8", args["provider_id"])
+
+	d = NewDeadLetter("worker.NotificationWatch", nil, 1, nil)
+	args, err = d.ArgsMap()
+	r.NoError(err)
+	r.Empty(args)
+}
@@ -13,23 +13,29 @@ import (
 
 // Incident is a struct for most atomic incident and event records.
 type Incident struct {
-	ID         uuid.UUID `json:"id" db:"id"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
-	Provider   string    `json:"provider" db:"provider"`
-	Type       string    `json:"type" db:"type"`
-	OriginalID string    `json:"original_id" db:"original_id"`
-	GroupID    string    `json:"group_id" db:"group_id"`
-	UserID     string    `json:"user_id" db:"user_id"`
-	Title      string    `json:"title" db:"title"`
-	Content    string    `json:"content" db:"content"`
-	Category   string    `json:"category" db:"category"`
-	Code       int       `json:"code" db:"code"`
-	IssuedBy   string    `json:"issued_by" db:"issued_by"`
-	IsOpen     bool      `json:"is_open" db:"is_open"`
-	IssuedAt   time.Time `json:"issued_at" db:"issued_at"`
-	ModifiedAt time.Time `json:"modified_at" db:"modified_at"`
-	Resources  Resources `many_to_many:"incidents_resources"`
+	ID             uuid.UUID      `json:"id" db:"id"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+	Provider       string         `json:"provider" db:"provider"`
+	Type           string         `json:"type" db:"type"`
+	OriginalID     string         `json:"original_id" db:"original_id"`
+	GroupID        string         `json:"group_id" db:"group_id"`
+	UserID         string         `json:"user_id" db:"user_id"`
+	Title          string         `json:"title" db:"title"`
+	Content        string         `json:"content" db:"content"`
+	Category       string         `json:"category" db:"category"`
+	Code           int            `json:"code" db:"code"`
+	IssuedBy       string         `json:"issued_by" db:"issued_by"`
+	IsOpen         bool           `json:"is_open" db:"is_open"`
+	IssuedAt       time.Time      `json:"issued_at" db:"issued_at"`
+	ModifiedAt     time.Time      `json:"modified_at" db:"modified_at"`
+	State          string         `json:"state" db:"state"`
+	StateChangedBy string         `json:"state_changed_by" db:"state_changed_by"`
+	StateChangedAt time.Time      `json:"state_changed_at" db:"state_changed_at"`
+	Resolution     string         `json:"resolution" db:"resolution"`
+	ParentID       uuid.UUID      `json:"parent_id" db:"parent_id"`
+	Resources      Resources      `many_to_many:"incidents_resources"`
+	Events         IncidentEvents `json:"events" has_many:"incident_events" order_by:"created_at"`
 }
 
 // IncidentsResources is structure for mapping incidents to resources
@@ -61,9 +67,94 @@ type Incidents []Incident
 
 //*** common database handling
 
-// Save just save given incident record
-func (i *Incident) Save() error {
-	return TrySave(i)
+// Upsert creates the incident or updates the stored one which has the same
+// origin. It returns the stored incident before the update, nil if created,
+// and whether the incident was created or changed. An incident not modified
+// since it was stored is not updated. Incidents closed on the provider are
+// closed, but they are reopened only on honcheonui.
+func (i *Incident) Upsert() (*Incident, bool, error) {
+	upstream := i.openness()
+	existing := i.Existing()
+	if existing == nil {
+		i.State = IncidentNew
+		if !i.IsOpen {
+			i.State = IncidentClosed
+		}
+		i.StateChangedBy = i.IssuedBy
+		i.StateChangedAt = time.Now()
+		verrs, err := DB.ValidateAndCreate(i)
+		if err != nil {
+			return nil, false, err
+		}
+		if verrs.HasAny() {
+			return nil, false, verrs
+		}
+		i.addSystemEvent(DB, EventUpstream, "reported by "+i.IssuedBy+" ("+upstream+")")
+		return nil, true, nil
+	}
+
+	// states are managed on honcheonui, not by the provider
+	i.ID = existing.ID
+	i.CreatedAt = existing.CreatedAt
+	i.State = existing.State
+	i.Resolution = existing.Resolution
+	i.StateChangedBy = existing.StateChangedBy
+	i.StateChangedAt = existing.StateChangedAt
+	i.ParentID = existing.ParentID
+	closed := !i.IsOpen && !existing.IsSettled()
+	if closed {
+		i.State = IncidentClosed
+		i.StateChangedBy = i.IssuedBy
+		i.StateChangedAt = time.Now()
+	}
+	i.IsOpen = !i.IsSettled()
+	if !i.IsChangedFrom(existing) {
+		i.UpdatedAt = existing.UpdatedAt
+		return existing, false, nil
+	}
+	verrs, err := DB.ValidateAndUpdate(i)
+	if err != nil {
+		return existing, false, err
+	}
+	if verrs.HasAny() {
+		return existing, false, verrs
+	}
+	i.addSystemEvent(DB, EventUpstream, "modified by "+i.IssuedBy+" ("+upstream+")")
+	if closed {
+		i.addSystemEvent(DB, EventState, existing.CurrentState()+" -> "+IncidentClosed)
+	}
+	return existing, true, nil
+}
+
+// openness returns whether the incident is open on the provider.
+func (i Incident) openness() string {
+	if i.IsOpen {
+		return "open"
+	}
+	return "closed"
+}
+
+// IsChangedFrom returns true if the incident has different contents from
+// the stored one.
+func (i Incident) IsChangedFrom(o *Incident) bool {
+	return !i.ModifiedAt.Equal(o.ModifiedAt) ||
+		i.IsOpen != o.IsOpen ||
+		i.Title != o.Title ||
+		i.Content != o.Content ||
+		i.Category != o.Category ||
+		i.Code != o.Code ||
+		i.IssuedBy != o.IssuedBy
+}
+
+// Existing returns stored incident which has the same origin with the
+// incident. It returns nil if there is no such incident.
+func (i *Incident) Existing() *Incident {
+	existing := &Incident{}
+	query := DB.Where("provider = ? AND type = ? AND original_id = ?", i.Provider, i.Type, i.OriginalID)
+	if err := query.First(existing); err != nil {
+		return nil
+	}
+	return existing
 }
 
 // LinkResourcesByOrigIDs makes a link map for incident to resources.
@@ -80,11 +171,7 @@ func (i *Incident) LinkResourcesByOrigIDs(IDs ...string) error {
 			}
 			continue
 		}
-		ir := &IncidentsResources{
-			ResourceID: resource.ID,
-			IncidentID: i.ID,
-		}
-		if err := TrySave(ir); err == nil {
+		if err := i.LinkResource(resource); err == nil {
 			success++
 		}
 	}
@@ -94,6 +181,28 @@ func (i *Incident) LinkResourcesByOrigIDs(IDs ...string) error {
 	return nil
 }
 
+// LinkResource makes a link map for incident to the resource if it is not
+// linked yet, and records it on the timeline.
+func (i *Incident) LinkResource(resource *Resource) error {
+	exists, err := DB.Where("incident_id = ? AND resource_id = ?", i.ID, resource.ID).Exists(&IncidentsResources{})
+	if err != nil {
+		mlogger.Errorf("database error: %v", err)
+		return err
+	}
+	if exists {
+		return nil
+	}
+	ir := &IncidentsResources{
+		ResourceID: resource.ID,
+		IncidentID: i.ID,
+	}
+	if err := TrySave(ir); err != nil {
+		return err
+	}
+	i.addSystemEvent(DB, EventResource, "linked resource "+resource.Name)
+	return nil
+}
+
 // LinkUsers makes a link map for incident to users.
 // It just make a link without checking user model since there is no such model currently.
 func (i *Incident) LinkUsers(IDs ...string) error {
@@ -108,6 +217,13 @@ func (i *Incident) LinkUsers(IDs ...string) error {
 	return nil
 }
 
+// BeforeSave derives IsOpen from the state so the state is the only
+// source of truth of the openness.
+func (i *Incident) BeforeSave(tx *pop.Connection) error {
+	i.IsOpen = !i.IsSettled()
+	return nil
+}
+
 //*** validators
 
 // Validate gets run every time you call a "pop.Validate*" method.
@@ -124,6 +240,7 @@ func (i *Incident) Validate(tx *pop.Connection) (*validate.Errors, error) {
 		&validators.StringIsPresent{Field: i.IssuedBy, Name: "IssuedBy"},
 		&validators.TimeIsPresent{Field: i.IssuedAt, Name: "IssuedAt"},
 		&validators.TimeIsPresent{Field: i.ModifiedAt, Name: "ModifiedAt"},
+		&validators.StringInclusion{Field: i.CurrentState(), Name: "State", List: IncidentStates},
 	), nil
 }
 
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// kinds of incident events
+const (
+	EventComment     = "comment"
+	EventState       = "state"
+	EventResource    = "resource"
+	EventUpstream    = "upstream"
+	EventCorrelation = "correlation"
+)
+
+// EventAuthorSystem is the author of events made by honcheonui itself.
+const EventAuthorSystem = "system"
+
+// IncidentEvent is an entry of the timeline of an incident. It is a
+// comment of a member or a system event such as state changes.
+type IncidentEvent struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	IncidentID uuid.UUID `json:"incident_id" db:"incident_id"`
+	Kind       string    `json:"kind" db:"kind"`
+	Author     string    `json:"author" db:"author"`
+	Message    string    `json:"message" db:"message"`
+}
+
+// IncidentEvents is an array of incident events
+type IncidentEvents []IncidentEvent
+
+// String returns the message of the event
+func (e IncidentEvent) String() string {
+	return e.Message
+}
+
+// IsComment returns true if the event is a comment of a member.
+func (e IncidentEvent) IsComment() bool {
+	return e.Kind == EventComment
+}
+
+// AddEvent appends an event to the timeline of the incident.
+func (i Incident) AddEvent(tx *pop.Connection, kind, author, message string) (*IncidentEvent, error) {
+	event := &IncidentEvent{
+		IncidentID: i.ID,
+		Kind:       kind,
+		Author:     author,
+		Message:    message,
+	}
+	verrs, err := tx.ValidateAndCreate(event)
+	if err != nil {
+		return event, err
+	}
+	if verrs.HasAny() {
+		return event, verrs
+	}
+	return event, nil
+}
+
+// addSystemEvent appends a system event and just logs the error since the
+// timeline should not break the operation which made the event.
+func (i Incident) addSystemEvent(tx *pop.Connection, kind, message string) {
+	if _, err := i.AddEvent(tx, kind, EventAuthorSystem, message); err != nil {
+		mlogger.Errorf("could not add %v event to incident %v: %v", kind, i.ID, err)
+	}
+}
+
+// Timeline returns events of the incident in time order.
+func (i Incident) Timeline(tx *pop.Connection) (*IncidentEvents, error) {
+	events := &IncidentEvents{}
+	err := tx.Where("incident_id = ?", i.ID).Order("created_at").All(events)
+	return events, err
+}
+
+//*** validators
+
+// Validate gets run every time you call a "pop.Validate*" method.
+func (e *IncidentEvent) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: e.IncidentID, Name: "IncidentID"},
+		&validators.StringInclusion{Field: e.Kind, Name: "Kind", List: []string{EventComment, EventState, EventResource, EventUpstream, EventCorrelation}},
+		&validators.StringIsPresent{Field: e.Author, Name: "Author"},
+		&validators.StringIsPresent{Field: e.Message, Name: "Message"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (e *IncidentEvent) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (e *IncidentEvent) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
@@ -0,0 +1,110 @@
+package models_test
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+func (ms *ModelSuite) incident() *models.Incident {
+	now := time.Now().Truncate(time.Second)
+	i := &models.Incident{
+		Provider: "fake", Type: "alert", OriginalID: "1", GroupID: "100", UserID: "1001",
+		Title: "disk full", Content: "disk is full", Category: "storage",
+		IssuedBy: "fake", IsOpen: true, IssuedAt: now, ModifiedAt: now,
+	}
+	_, _, err := i.Upsert()
+	ms.NoError(err)
+	return i
+}
+
+func (ms *ModelSuite) Test_Incident_AddEvent() {
+	i := ms.incident()
+
+	_, err := i.AddEvent(ms.DB, models.EventComment, "tester@example.com", "")
+	ms.Error(err, "empty message")
+	_, err = i.AddEvent(ms.DB, "unknown", "tester@example.com", "hello")
+	ms.Error(err, "unknown kind")
+
+	event, err := i.AddEvent(ms.DB, models.EventComment, "tester@example.com", "checking")
+	ms.NoError(err)
+	ms.True(event.IsComment())
+	ms.Equal(i.ID, event.IncidentID)
+
+	count, err := ms.DB.Where("incident_id = ?", i.ID).Count(&models.IncidentEvent{})
+	ms.NoError(err)
+	ms.Equal(2, count, "upstream and the comment")
+}
+
+func (ms *ModelSuite) Test_Incident_Timeline() {
+	i := ms.incident()
+	ms.NoError(i.Transition(ms.DB, models.IncidentAcknowledged, "tester@example.com", ""))
+	_, err := i.AddEvent(ms.DB, models.EventComment, "tester@example.com", "checking")
+	ms.NoError(err)
+	ms.NoError(i.Transition(ms.DB, models.IncidentResolved, "", "disk cleaned"))
+
+	events, err := i.Timeline(ms.DB)
+	ms.NoError(err)
+	ms.Len(*events, 4)
+	for k, expected := range []struct{ kind, author, message string }{
+		{models.EventUpstream, models.EventAuthorSystem, "reported by fake (open)"},
+		{models.EventState, "tester@example.com", "new -> acknowledged"},
+		{models.EventComment, "tester@example.com", "checking"},
+		{models.EventState, models.EventAuthorSystem, "acknowledged -> resolved\ndisk cleaned"},
+	} {
+		e := (*events)[k]
+		ms.Equal(expected.kind, e.Kind, k)
+		ms.Equal(expected.author, e.Author, k)
+		ms.Equal(expected.message, e.Message, k)
+	}
+	for k := 1; k < len(*events); k++ {
+		ms.False((*events)[k].CreatedAt.Before((*events)[k-1].CreatedAt))
+	}
+
+	ms.Equal(models.ErrInvalidTransition, i.Transition(ms.DB, models.IncidentNew, "", ""))
+	events, err = i.Timeline(ms.DB)
+	ms.NoError(err)
+	ms.Len(*events, 4, "failed transition should not be recorded")
+}
+
+func (ms *ModelSuite) Test_Incident_Upsert_Events() {
+	i := ms.incident()
+
+	// unchanged upstream incident adds no events
+	same := *i
+	same.ID = uuid.Nil
+	_, changed, err := same.Upsert()
+	ms.NoError(err)
+	ms.False(changed)
+
+	modified := *i
+	modified.IsOpen = false
+	modified.ModifiedAt = i.ModifiedAt.Add(time.Minute)
+	_, changed, err = modified.Upsert()
+	ms.NoError(err)
+	ms.True(changed)
+
+	ms.Equal(models.IncidentClosed, modified.State)
+	ms.False(modified.IsOpen)
+
+	events, err := i.Timeline(ms.DB)
+	ms.NoError(err)
+	ms.Len(*events, 3)
+	ms.Equal("reported by fake (open)", (*events)[0].Message)
+	ms.Equal(models.EventUpstream, (*events)[1].Kind)
+	ms.Equal(models.EventAuthorSystem, (*events)[1].Author)
+	ms.Equal("modified by fake (closed)", (*events)[1].Message)
+	ms.Equal(models.EventState, (*events)[2].Kind)
+	ms.Equal("new -> closed", (*events)[2].Message)
+
+	// the provider could not reopen the incident
+	reopened := modified
+	reopened.IsOpen = true
+	reopened.ModifiedAt = modified.ModifiedAt.Add(time.Minute)
+	_, _, err = reopened.Upsert()
+	ms.NoError(err)
+	ms.Equal(models.IncidentClosed, reopened.State)
+	ms.False(reopened.IsOpen)
+}
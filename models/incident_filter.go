@@ -0,0 +1,73 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+)
+
+// IncidentFilter is a set of conditions to list incidents. Empty fields
+// are not applied. IsOpen is nil for both open and closed incidents.
+type IncidentFilter struct {
+	Provider   string
+	Category   string
+	Type       string
+	State      string
+	IsOpen     *bool
+	IssuedFrom time.Time
+	IssuedTo   time.Time
+	ResourceID uuid.UUID
+	ServiceID  uuid.UUID
+	Query      string
+}
+
+// IsEmpty returns true if no condition is set.
+func (f IncidentFilter) IsEmpty() bool {
+	return f == IncidentFilter{}
+}
+
+// Apply adds the conditions to the query of incidents. Linked resources and
+// services are matched by subqueries so incidents are not duplicated.
+func (f IncidentFilter) Apply(q *pop.Query) *pop.Query {
+	if f.Provider != "" {
+		q = q.Where("incidents.provider = ?", f.Provider)
+	}
+	if f.Category != "" {
+		q = q.Where("incidents.category = ?", f.Category)
+	}
+	if f.Type != "" {
+		q = q.Where("incidents.type = ?", f.Type)
+	}
+	if f.State != "" {
+		q = q.Where("incidents.state = ?", f.State)
+	}
+	if f.IsOpen != nil {
+		q = q.Where("incidents.is_open = ?", *f.IsOpen)
+	}
+	if !f.IssuedFrom.IsZero() {
+		q = q.Where("incidents.issued_at >= ?", f.IssuedFrom)
+	}
+	if !f.IssuedTo.IsZero() {
+		q = q.Where("incidents.issued_at < ?", f.IssuedTo)
+	}
+	if f.ResourceID != uuid.Nil {
+		q = q.Where("incidents.id IN (SELECT incident_id FROM incidents_resources"+
+			" WHERE resource_id = ?)", f.ResourceID)
+	}
+	if f.ServiceID != uuid.Nil {
+		q = q.Where("incidents.id IN (SELECT incidents_resources.incident_id FROM incidents_resources"+
+			" JOIN resources_tags ON resources_tags.resource_id = incidents_resources.resource_id"+
+			" JOIN services_tags ON services_tags.tag_id = resources_tags.tag_id"+
+			" WHERE services_tags.service_id = ?)", f.ServiceID)
+	}
+	if f.Query != "" {
+		like := "%" + likeEscaper.Replace(f.Query) + "%"
+		q = q.Where("(incidents.title LIKE ? OR incidents.content LIKE ?)", like, like)
+	}
+	return q
+}
+
+// likeEscaper escapes wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
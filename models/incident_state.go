@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+)
+
+// states of incidents on honcheonui. They are managed by members, and the
+// provider could only close incidents. IsOpen is derived from them.
+const (
+	IncidentNew           = "new"
+	IncidentAcknowledged  = "acknowledged"
+	IncidentInvestigating = "investigating"
+	IncidentResolved      = "resolved"
+	IncidentClosed        = "closed"
+)
+
+// errors for incident state transitions
+var (
+	ErrInvalidTransition  = errors.New("invalid state transition")
+	ErrResolutionRequired = errors.New("resolution is required")
+)
+
+// IncidentStates is the list of all incident states in lifecycle order.
+var IncidentStates = []string{
+	IncidentNew,
+	IncidentAcknowledged,
+	IncidentInvestigating,
+	IncidentResolved,
+	IncidentClosed,
+}
+
+// incidentTransitions maps states to states they could move to. Resolved
+// and closed incidents could be reopened for investigation.
+var incidentTransitions = map[string][]string{
+	IncidentNew:           {IncidentAcknowledged, IncidentInvestigating, IncidentResolved, IncidentClosed},
+	IncidentAcknowledged:  {IncidentInvestigating, IncidentResolved, IncidentClosed},
+	IncidentInvestigating: {IncidentResolved, IncidentClosed},
+	IncidentResolved:      {IncidentInvestigating, IncidentClosed},
+	IncidentClosed:        {IncidentInvestigating},
+}
+
+// NextStates returns states the incident could move to.
+func (i Incident) NextStates() []string {
+	return incidentTransitions[i.CurrentState()]
+}
+
+// CurrentState returns the state of the incident. Incidents stored before
+// the state was introduced are new.
+func (i Incident) CurrentState() string {
+	if i.State == "" {
+		return IncidentNew
+	}
+	return i.State
+}
+
+// CanTransitionTo returns true if the incident could move to the state.
+func (i Incident) CanTransitionTo(state string) bool {
+	for _, s := range i.NextStates() {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSettled returns true if the incident is resolved or closed.
+func (i Incident) IsSettled() bool {
+	s := i.CurrentState()
+	return s == IncidentResolved || s == IncidentClosed
+}
+
+// Transition moves the incident to the state by the member. Resolution is
+// required to resolve the incident, and kept until it is resolved again.
+func (i *Incident) Transition(tx *pop.Connection, state, by, resolution string) error {
+	if !i.CanTransitionTo(state) {
+		return ErrInvalidTransition
+	}
+	if state == IncidentResolved {
+		if resolution == "" {
+			return ErrResolutionRequired
+		}
+		i.Resolution = resolution
+	} else if resolution != "" {
+		i.Resolution = resolution
+	}
+	from := i.CurrentState()
+	i.State = state
+	i.StateChangedBy = by
+	i.StateChangedAt = time.Now()
+
+	verrs, err := tx.ValidateAndUpdate(i)
+	if err != nil {
+		return err
+	}
+	if verrs.HasAny() {
+		return verrs
+	}
+
+	if by == "" {
+		by = EventAuthorSystem
+	}
+	message := from + " -> " + state
+	if state == IncidentResolved {
+		message += "\n" + resolution
+	}
+	// the state should not be changed without its event
+	if _, err := i.AddEvent(tx, EventState, by, message); err != nil {
+		mlogger.Errorf("could not add state event to incident %v: %v", i.ID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Incident_Transitions(t *testing.T) {
+	r := require.New(t)
+
+	i := Incident{}
+	r.Equal(IncidentNew, i.CurrentState())
+	r.True(i.CanTransitionTo(IncidentAcknowledged))
+	r.True(i.CanTransitionTo(IncidentResolved))
+	r.False(i.CanTransitionTo(IncidentNew))
+	r.False(i.IsSettled())
+
+	i.State = IncidentInvestigating
+	r.False(i.CanTransitionTo(IncidentAcknowledged))
+	r.True(i.CanTransitionTo(IncidentResolved))
+
+	i.State = IncidentResolved
+	r.True(i.IsSettled())
+	r.Equal([]string{IncidentInvestigating, IncidentClosed}, i.NextStates())
+
+	i.State = IncidentClosed
+	r.True(i.CanTransitionTo(IncidentInvestigating))
+	r.False(i.CanTransitionTo(IncidentResolved))
+}
+
+func Test_Incident_Transition_Invalid(t *testing.T) {
+	r := require.New(t)
+
+	i := &Incident{State: IncidentClosed}
+	r.Equal(ErrInvalidTransition, i.Transition(nil, IncidentResolved, "ops@example.com", "fixed"))
+	i.State = IncidentInvestigating
+	r.Equal(ErrResolutionRequired, i.Transition(nil, IncidentResolved, "ops@example.com", ""))
+	r.Equal(IncidentInvestigating, i.State)
+}
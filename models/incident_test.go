@@ -1,7 +1,67 @@
 package models
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
 
 func Test_Incident(t *testing.T) {
 	t.Fatal("This test needs to be implemented!")
 }
+
+func Test_Incident_IsChangedFrom(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Now()
+	stored := &Incident{Title: "maintenance", Content: "reboot", IsOpen: true, ModifiedAt: now}
+	incoming := *stored
+	r.False(incoming.IsChangedFrom(stored))
+
+	incoming.ModifiedAt = now.Add(time.Minute)
+	r.True(incoming.IsChangedFrom(stored))
+
+	incoming = *stored
+	incoming.IsOpen = false
+	r.True(incoming.IsChangedFrom(stored))
+
+	incoming = *stored
+	incoming.Content = "reboot and upgrade"
+	r.True(incoming.IsChangedFrom(stored))
+}
+
+func Test_Incident_BeforeSave(t *testing.T) {
+	r := require.New(t)
+
+	i := &Incident{IsOpen: false}
+	r.NoError(i.BeforeSave(nil))
+	r.True(i.IsOpen, "new incidents are open")
+	for state, open := range map[string]bool{
+		IncidentAcknowledged:  true,
+		IncidentInvestigating: true,
+		IncidentResolved:      false,
+		IncidentClosed:        false,
+	} {
+		i.State = state
+		r.NoError(i.BeforeSave(nil))
+		r.Equal(open, i.IsOpen, state)
+	}
+}
+
+func Test_IncidentFilter_IsEmpty(t *testing.T) {
+	r := require.New(t)
+
+	r.True(IncidentFilter{}.IsEmpty())
+	open := false
+	r.False(IncidentFilter{IsOpen: &open}.IsEmpty())
+	r.False(IncidentFilter{Query: "disk"}.IsEmpty())
+	r.False(IncidentFilter{IssuedFrom: time.Now()}.IsEmpty())
+}
+
+func Test_LikeEscaper(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(`100\% cpu\_usage`, likeEscaper.Replace("100% cpu_usage"))
+	r.Equal(`c:\\temp`, likeEscaper.Replace(`c:\temp`))
+}
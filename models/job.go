@@ -0,0 +1,191 @@
+package models
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/hyeoncheon/honcheonui/utils"
+)
+
+// Job is a queued background job. Pending jobs with the same queue,
+// handler, and arguments are unique by UniqueKey. A job is claimed by a
+// worker instance with lease and removed when it is done, so the job could
+// be claimed again by another instance if the lease is expired.
+type Job struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	Queue       string    `json:"queue" db:"queue"`
+	Handler     string    `json:"handler" db:"handler"`
+	Args        string    `json:"args" db:"args"`
+	RunAt       time.Time `json:"run_at" db:"run_at"`
+	LockedBy    string    `json:"locked_by" db:"locked_by"`
+	LockedUntil time.Time `json:"locked_until" db:"locked_until"`
+	UniqueKey   string    `json:"-" db:"unique_key"`
+}
+
+// Jobs is an array of jobs
+type Jobs []Job
+
+// String returns the handler of the job
+func (j Job) String() string {
+	return j.Handler
+}
+
+// NewJob returns a job for the handler to be run at given time.
+func NewJob(queue, handler string, args map[string]interface{}, runAt time.Time) *Job {
+	j := &Job{
+		Queue:       queue,
+		Handler:     handler,
+		Args:        "{}",
+		RunAt:       runAt,
+		LockedUntil: time.Unix(0, 0).UTC(), // not claimed yet
+	}
+	if jb, err := json.Marshal(args); err == nil && args != nil {
+		j.Args = string(jb)
+	}
+	j.SetUniqueKey(queue, handler, j.Args)
+	return j
+}
+
+// SetUniqueKey sets the unique key of the job from given parts. By default,
+// the key is made from the queue, handler, and arguments.
+func (j *Job) SetUniqueKey(parts ...string) {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\n")))
+	j.UniqueKey = hex.EncodeToString(sum[:])
+}
+
+// ArgsMap returns the arguments of the job as a map.
+func (j Job) ArgsMap() (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	if j.Args == "" {
+		return args, nil
+	}
+	err := json.Unmarshal([]byte(j.Args), &args)
+	return args, err
+}
+
+// IsRunning returns true if the job is claimed and the lease is alive.
+func (j Job) IsRunning() bool {
+	return j.LockedBy != "" && j.LockedUntil.After(time.Now())
+}
+
+// Enqueue stores the job within the transaction. It returns false without
+// error if the same job is already pending.
+func (j *Job) Enqueue(tx *pop.Connection) (bool, error) {
+	exists, err := tx.Where("unique_key = ?", j.UniqueKey).Exists(&Job{})
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+	if err := tx.Create(j); err != nil {
+		// the same job could be queued by another instance at the moment
+		if exists, _ := tx.Where("unique_key = ?", j.UniqueKey).Exists(&Job{}); exists {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ClaimJobs claims jobs of given handlers due now for the owner with
+// lease, up to limit. Jobs of other handlers are left for instances which
+// have them. A job is claimed by only one owner even if the owners run
+// concurrently. The unique key of claimed jobs is released so the same job
+// could be queued again while it is running.
+func ClaimJobs(owner string, lease time.Duration, limit int, handlers []string) (Jobs, error) {
+	if len(handlers) == 0 {
+		return Jobs{}, nil
+	}
+	names, err := utils.ToInterface(handlers)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	candidates := &Jobs{}
+	err = DB.Where("run_at <= ? AND locked_until < ?", now, now).
+		Where("handler IN (?)", names...).
+		Order("run_at").Limit(limit).All(candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := Jobs{}
+	until := now.Add(lease)
+	for _, j := range *candidates {
+		count, err := DB.RawQuery(`UPDATE jobs
+			SET locked_by = ?, locked_until = ?, unique_key = ?, updated_at = ?
+			WHERE id = ? AND locked_until < ?`,
+			owner, until, j.ID.String(), now, j.ID, now).ExecWithCount()
+		if err != nil {
+			return claimed, err
+		}
+		if count == 1 {
+			j.LockedBy = owner
+			j.LockedUntil = until
+			claimed = append(claimed, j)
+		}
+	}
+	return claimed, nil
+}
+
+// ExtendLease extends the lease of the job if it is still owned.
+func (j *Job) ExtendLease(lease time.Duration) error {
+	until := time.Now().Add(lease)
+	err := DB.RawQuery("UPDATE jobs SET locked_until = ? WHERE id = ? AND locked_by = ?",
+		until, j.ID, j.LockedBy).Exec()
+	if err == nil {
+		j.LockedUntil = until
+	}
+	return err
+}
+
+// Release gives up the lease of the job if it is still owned so the job
+// could be claimed again.
+func (j *Job) Release() error {
+	return DB.RawQuery("UPDATE jobs SET locked_by = '', locked_until = ? WHERE id = ? AND locked_by = ?",
+		time.Unix(0, 0).UTC(), j.ID, j.LockedBy).Exec()
+}
+
+// Done removes the job if it is still owned.
+func (j *Job) Done() error {
+	return DB.RawQuery("DELETE FROM jobs WHERE id = ? AND locked_by = ?", j.ID, j.LockedBy).Exec()
+}
+
+// PendingJobs returns all jobs in the queue including running ones.
+func PendingJobs() *Jobs {
+	jobs := &Jobs{}
+	if err := DB.Order("run_at").All(jobs); err != nil {
+		mlogger.Errorf("could not get jobs: %v", err)
+	}
+	return jobs
+}
+
+//*** validators
+
+// Validate gets run every time you call a "pop.Validate*" method.
+func (j *Job) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: j.Handler, Name: "Handler"},
+		&validators.StringIsPresent{Field: j.UniqueKey, Name: "UniqueKey"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (j *Job) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (j *Job) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
@@ -0,0 +1,137 @@
+package models_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+const testHandler = "worker.Test"
+
+func (ms *ModelSuite) enqueueJob(args map[string]interface{}) *models.Job {
+	j := models.NewJob("default", testHandler, args, time.Now().Add(-time.Minute))
+	queued, err := j.Enqueue(ms.DB)
+	ms.NoError(err)
+	ms.True(queued)
+	return j
+}
+
+func (ms *ModelSuite) Test_Job_Enqueue() {
+	ms.enqueueJob(map[string]interface{}{"provider_id": "a"})
+
+	// the same pending job is not queued again
+	dup := models.NewJob("default", testHandler, map[string]interface{}{"provider_id": "a"}, time.Now())
+	queued, err := dup.Enqueue(ms.DB)
+	ms.NoError(err)
+	ms.False(queued)
+
+	ms.enqueueJob(map[string]interface{}{"provider_id": "b"})
+	count, err := ms.DB.Count(&models.Jobs{})
+	ms.NoError(err)
+	ms.Equal(2, count)
+
+	// the unique key is released when the job is claimed
+	jobs, err := models.ClaimJobs("host-1", time.Minute, 10, []string{testHandler})
+	ms.NoError(err)
+	ms.Len(jobs, 2)
+	queued, err = dup.Enqueue(ms.DB)
+	ms.NoError(err)
+	ms.True(queued)
+}
+
+func (ms *ModelSuite) Test_ClaimJobs_Exclusive() {
+	for i := 0; i < 20; i++ {
+		ms.enqueueJob(map[string]interface{}{"n": i})
+	}
+	other := models.NewJob("default", "worker.Other", nil, time.Now().Add(-time.Minute))
+	_, err := other.Enqueue(ms.DB)
+	ms.NoError(err)
+
+	// two owners claim at the same time but each job is claimed once
+	claimed := map[string][]models.Job{}
+	errs := []error{}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, owner := range []string{"host-1", "host-2"} {
+		wg.Add(1)
+		go func(owner string) {
+			defer wg.Done()
+			for {
+				jobs, err := models.ClaimJobs(owner, time.Minute, 3, []string{testHandler})
+				mutex.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				}
+				claimed[owner] = append(claimed[owner], jobs...)
+				mutex.Unlock()
+				if err != nil || len(jobs) == 0 {
+					return
+				}
+			}
+		}(owner)
+	}
+	wg.Wait()
+	ms.Len(errs, 0)
+
+	seen := map[string]bool{}
+	for owner, jobs := range claimed {
+		for _, j := range jobs {
+			ms.Equal(owner, j.LockedBy)
+			ms.False(seen[j.ID.String()], "claimed twice")
+			seen[j.ID.String()] = true
+		}
+	}
+	ms.Len(seen, 20)
+
+	// jobs of handlers not given are not claimed
+	ms.NoError(ms.DB.Reload(other))
+	ms.Equal("", other.LockedBy)
+	jobs, err := models.ClaimJobs("host-1", time.Minute, 10, nil)
+	ms.NoError(err)
+	ms.Len(jobs, 0)
+}
+
+func (ms *ModelSuite) Test_ClaimJobs_LeaseExpired() {
+	ms.enqueueJob(nil)
+
+	// the lease of host-1 is already expired
+	jobs, err := models.ClaimJobs("host-1", -time.Minute, 10, []string{testHandler})
+	ms.NoError(err)
+	ms.Len(jobs, 1)
+	expired := jobs[0]
+
+	jobs, err = models.ClaimJobs("host-2", time.Minute, 10, []string{testHandler})
+	ms.NoError(err)
+	ms.Len(jobs, 1)
+	ms.Equal(expired.ID, jobs[0].ID)
+	ms.True(jobs[0].IsRunning())
+
+	// not claimed again while the lease is alive
+	again, err := models.ClaimJobs("host-1", time.Minute, 10, []string{testHandler})
+	ms.NoError(err)
+	ms.Len(again, 0)
+
+	// the job is removed only by the current owner
+	ms.NoError(expired.Done())
+	count, err := ms.DB.Count(&models.Jobs{})
+	ms.NoError(err)
+	ms.Equal(1, count)
+	ms.NoError(jobs[0].Done())
+	count, err = ms.DB.Count(&models.Jobs{})
+	ms.NoError(err)
+	ms.Equal(0, count)
+}
+
+func (ms *ModelSuite) Test_Job_Release() {
+	ms.enqueueJob(nil)
+	jobs, err := models.ClaimJobs("host-1", time.Minute, 10, []string{testHandler})
+	ms.NoError(err)
+	ms.Len(jobs, 1)
+
+	ms.NoError(jobs[0].Release())
+	jobs, err = models.ClaimJobs("host-2", time.Minute, 10, []string{testHandler})
+	ms.NoError(err)
+	ms.Len(jobs, 1)
+	ms.Equal("host-2", jobs[0].LockedBy)
+}
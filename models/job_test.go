@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewJob(t *testing.T) {
+	r := require.New(t)
+	now := time.Now()
+
+	j1 := NewJob("default", "worker.ResourceSync", map[string]interface{}{"provider_id": "a"}, now)
+	j2 := NewJob("default", "worker.ResourceSync", map[string]interface{}{"provider_id": "a"}, now.Add(time.Hour))
+	j3 := NewJob("default", "worker.ResourceSync", map[string]interface{}{"provider_id": "b"}, now)
+	j4 := NewJob("default", "worker.ResourceSync", nil, now)
+	r.Equal(j1.UniqueKey, j2.UniqueKey)
+	r.NotEqual(j1.UniqueKey, j3.UniqueKey)
+	r.NotEqual(j1.UniqueKey, j4.UniqueKey)
+	r.Equal("{}", j4.Args)
+	r.False(j1.IsRunning())
+
+	args, err := j1.ArgsMap()
+	r.NoError(err)
+	r.Equal("a", args["provider_id"])
+
+	j1.SetUniqueKey("default", "worker.Repeater", "worker.ResourceSync")
+	r.Len(j1.UniqueKey, 40)
+
+	j1.LockedBy = "host-1"
+	j1.LockedUntil = now.Add(time.Minute)
+	r.True(j1.IsRunning())
+}
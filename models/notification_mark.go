@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// NotificationMark is the high-water mark of notifications of a provider.
+// It keeps the last modified time of notifications seen so the next watch
+// fetches only new or updated ones.
+type NotificationMark struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	ProviderID uuid.UUID `json:"provider_id" db:"provider_id"`
+	SeenAt     time.Time `json:"seen_at" db:"seen_at"`
+}
+
+// String returns the time of the mark
+func (m NotificationMark) String() string {
+	return m.SeenAt.Format(time.RFC3339)
+}
+
+// NotificationMark returns the last modified time of notifications seen
+// for the provider, or zero time if notifications were never watched.
+func (p Provider) NotificationMark() time.Time {
+	mark := &NotificationMark{}
+	if err := DB.Where("provider_id = ?", p.ID).First(mark); err != nil {
+		return time.Time{}
+	}
+	return mark.SeenAt
+}
+
+// NotificationsSince returns the time to fetch notifications from. It is
+// the mark of the provider, but not older than the lookback from now.
+func (p Provider) NotificationsSince(now time.Time, lookback time.Duration) time.Time {
+	since := now.Add(-lookback)
+	if mark := p.NotificationMark(); mark.After(since) {
+		return mark
+	}
+	return since
+}
+
+// SetNotificationMark moves the mark of the provider forward to the time.
+// The mark is never moved backward.
+func (p Provider) SetNotificationMark(seenAt time.Time) error {
+	mark := &NotificationMark{}
+	if err := DB.Where("provider_id = ?", p.ID).First(mark); err != nil {
+		mark = &NotificationMark{ProviderID: p.ID}
+	} else if !seenAt.After(mark.SeenAt) {
+		return nil
+	}
+	mark.SeenAt = seenAt
+	verrs, err := DB.ValidateAndSave(mark)
+	if err != nil {
+		return err
+	}
+	if verrs.HasAny() {
+		return verrs
+	}
+	return nil
+}
+
+//*** validators
+
+// Validate gets run every time you call a "pop.Validate*" method.
+func (m *NotificationMark) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: m.ProviderID, Name: "ProviderID"},
+		&validators.TimeIsPresent{Field: m.SeenAt, Name: "SeenAt"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (m *NotificationMark) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (m *NotificationMark) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
@@ -1,13 +1,17 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/gobuffalo/envy"
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
 	"github.com/gofrs/uuid"
+	"github.com/hyeoncheon/honcheonui/secrets"
 	"github.com/hyeoncheon/honcheonui/utils"
 )
 
@@ -19,13 +23,17 @@ type Provider struct {
 	MemberID  uuid.UUID `json:"member_id" db:"member_id"`
 	Provider  string    `json:"provider" db:"provider"`
 	User      string    `json:"user" db:"user"`
-	Pass      string    `json:"pass" db:"pass"`
+	Pass      string    `json:"pass,omitempty" db:"pass"`
 	GroupID   string    `json:"group_id" db:"group_id"`
 	UserID    string    `json:"user_id" db:"user_id"`
 	Member    Member    `belongs_to:"member"`
 	Resources Resources `many_to_many:"providers_resources"`
 }
 
+// ErrNoSecretKey is returned when secret fields could not be encrypted
+// since HCU_SECRET_KEY is not set.
+var ErrNoSecretKey = errors.New("HCU_SECRET_KEY is not set. it is required to store credentials of providers")
+
 // ProvidersResources is a map between provider and resource
 type ProvidersResources struct {
 	ID         uuid.UUID `json:"id" db:"id"`
@@ -40,6 +48,15 @@ func (p Provider) String() string {
 	return p.Provider + "/" + p.User
 }
 
+// MarshalJSON returns json of the provider without its password. The
+// password could be given by json request bodies but is never written out.
+func (p Provider) MarshalJSON() ([]byte, error) {
+	type provider Provider
+	out := provider(p)
+	out.Pass = ""
+	return json.Marshal(out)
+}
+
 // Owner returns owner of the provider entity
 // DEPRECATED: now buffalo support assotiation more easily with Eager() and Load()
 func (p Provider) Owner() *Member {
@@ -51,6 +68,36 @@ func (p Provider) Owner() *Member {
 	return member
 }
 
+// Credentials returns user and decrypted password of the provider. It
+// should be called only when the credential is handed to a plugin.
+// If they are secret references such as "secret:env:HCU_SECRET_SL_KEY",
+// they are resolved via secret backends.
+// Passwords stored before encryption was introduced are returned as they
+// are until the key rotation task encrypts them.
+func (p Provider) Credentials() (string, string, error) {
+	pass := p.Pass
+	if utils.IsEncrypted(pass) {
+		var err error
+		if pass, err = utils.Decrypt(secretKey(), pass); err != nil {
+			slogger.Errorf("could not decrypt password of provider %v: %v", p.ID, err)
+			return "", "", err
+		}
+	} else if p.ID != uuid.Nil {
+		slogger.Warnf("password of provider %v is not encrypted", p.ID)
+	}
+
+	user, err := secrets.Resolve(p.User)
+	if err != nil {
+		slogger.Errorf("could not resolve user of provider %v: %v", p.ID, err)
+		return "", "", err
+	}
+	if pass, err = secrets.Resolve(pass); err != nil {
+		slogger.Errorf("could not resolve password of provider %v: %v", p.ID, err)
+		return "", "", err
+	}
+	return user, pass, nil
+}
+
 // Providers is an array of providers
 type Providers []Provider
 
@@ -60,7 +107,7 @@ type ProvidersResourcesMaps []ProvidersResources
 //*** relationship
 
 // LinkResources makes a link map of provider and resources
-func (p *Provider) LinkResources(oids []uuid.UUID) error {
+func (p *Provider) LinkResources(tx *pop.Connection, oids []uuid.UUID) error {
 	ids, err := utils.ToInterface(oids)
 	if err != nil {
 		return errors.New("could not convert argument to interface slice")
@@ -70,7 +117,7 @@ func (p *Provider) LinkResources(oids []uuid.UUID) error {
 
 	// get existing mappings and remove them from given list
 	maps := &ProvidersResourcesMaps{}
-	if err := DB.Where("provider_id = ?", p.ID).All(maps); err != nil {
+	if err := tx.Where("provider_id = ?", p.ID).All(maps); err != nil {
 		mlogger.Errorf("database selection failed! error: %v", err)
 	}
 	for _, m := range *maps {
@@ -80,7 +127,7 @@ func (p *Provider) LinkResources(oids []uuid.UUID) error {
 			mlogger.Debugf("removed existing resource from list: %v", ids)
 		} else {
 			mlogger.Debugf("removing %v from map. no longer exists", m.ResourceID)
-			if err := DB.Destroy(&m); err != nil {
+			if err := tx.Destroy(&m); err != nil {
 				mlogger.Errorf("could not remove resource %v from the map", m)
 				hasError = true
 			}
@@ -94,7 +141,7 @@ func (p *Provider) LinkResources(oids []uuid.UUID) error {
 			ProviderID: p.ID,
 			ResourceID: id.(uuid.UUID), //! check me
 		}
-		if err := DB.Save(prmap); err != nil {
+		if err := tx.Save(prmap); err != nil {
 			mlogger.Errorf("could not save provider-resource map %v: %v", prmap, err)
 			hasError = true
 		}
@@ -105,6 +152,93 @@ func (p *Provider) LinkResources(oids []uuid.UUID) error {
 	return nil
 }
 
+//*** secrets
+
+// secrets returns pointers of secret fields which should be encrypted.
+// Add new API key fields here to encrypt them at rest.
+func (p *Provider) secrets() []*string {
+	return []*string{&p.Pass}
+}
+
+// BeforeSave encrypts secret fields before they are stored.
+func (p *Provider) BeforeSave(tx *pop.Connection) error {
+	err := encryptSecrets(secretKey(), p.secrets()...)
+	if err == utils.ErrNoKey {
+		return ErrNoSecretKey
+	}
+	return err
+}
+
+// RotateProviderKey re-encrypts secret fields of all providers with the
+// new key. Values encrypted with the old key are decrypted first and plain
+// values are just encrypted. It returns the number of updated providers.
+func RotateProviderKey(oldKey, newKey string) (int, error) {
+	count := 0
+	err := DB.Transaction(func(tx *pop.Connection) error {
+		providers := &Providers{}
+		if err := tx.All(providers); err != nil {
+			return err
+		}
+		for _, provider := range *providers {
+			secrets := provider.secrets()
+			if err := decryptSecrets(oldKey, secrets...); err != nil {
+				return fmt.Errorf("provider %v: %v", provider.ID, err)
+			}
+			if err := encryptSecrets(newKey, secrets...); err != nil {
+				return err
+			}
+			if err := tx.Update(&provider); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// secretKey returns the application key for secret fields.
+func secretKey() string {
+	return envy.Get("HCU_SECRET_KEY", "")
+}
+
+// CheckSecretKey returns ErrNoSecretKey if the application key for secret
+// fields is not set. It should be checked on boot.
+func CheckSecretKey() error {
+	if secretKey() == "" {
+		return ErrNoSecretKey
+	}
+	return nil
+}
+
+func encryptSecrets(key string, fields ...*string) error {
+	for _, f := range fields {
+		if *f == "" || utils.IsEncrypted(*f) {
+			continue
+		}
+		enc, err := utils.Encrypt(key, *f)
+		if err != nil {
+			return err
+		}
+		*f = enc
+	}
+	return nil
+}
+
+func decryptSecrets(key string, fields ...*string) error {
+	for _, f := range fields {
+		if !utils.IsEncrypted(*f) {
+			continue
+		}
+		plain, err := utils.Decrypt(key, *f)
+		if err != nil {
+			return err
+		}
+		*f = plain
+	}
+	return nil
+}
+
 //*** validators
 
 // Validate gets run every time you call a "pop.Validate*" method.
@@ -1,7 +1,46 @@
 package models
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyeoncheon/honcheonui/utils"
+)
 
 func Test_Provider(t *testing.T) {
 	t.Fatal("This test needs to be implemented!")
 }
+
+func Test_Provider_MarshalJSON(t *testing.T) {
+	r := require.New(t)
+
+	p := &Provider{}
+	r.NoError(json.Unmarshal([]byte(`{"provider":"fake","user":"fake","pass":"secret"}`), p))
+	r.Equal("secret", p.Pass)
+
+	out, err := json.Marshal(p)
+	r.NoError(err)
+	r.NotContains(string(out), "secret")
+	r.Contains(string(out), `"user":"fake"`)
+	r.Equal("secret", p.Pass)
+}
+
+func Test_Provider_BeforeSave(t *testing.T) {
+	r := require.New(t)
+	key := envy.Get("HCU_SECRET_KEY", "")
+	defer envy.Set("HCU_SECRET_KEY", key)
+
+	envy.Set("HCU_SECRET_KEY", "")
+	r.Equal(ErrNoSecretKey, CheckSecretKey())
+	p := &Provider{Pass: "secret"}
+	r.Equal(ErrNoSecretKey, p.BeforeSave(nil))
+	r.Equal("secret", p.Pass)
+
+	envy.Set("HCU_SECRET_KEY", "test-secret-key")
+	r.NoError(CheckSecretKey())
+	r.NoError(p.BeforeSave(nil))
+	r.True(utils.IsEncrypted(p.Pass))
+}
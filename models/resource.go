@@ -3,6 +3,8 @@ package models
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -13,31 +15,42 @@ import (
 	"github.com/hyeoncheon/honcheonui/utils"
 )
 
+// lifecycle states of resources. resources disappeared from the provider
+// are marked as missing, and retired after grace period.
+const (
+	ResourceActive  = "active"
+	ResourceMissing = "missing"
+	ResourceRetired = "retired"
+)
+
 // Resource is struct for storing atomic monitoring target resources.
 // For support general resources from different kind of services,
 // it just contains common attributes and provider specific attributes
 // are stored separately on Attribute model.
 type Resource struct {
-	ID                 uuid.UUID  `json:"id" db:"id"`
-	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
-	Provider           string     `json:"provider" db:"provider"`
-	Type               string     `json:"type" db:"type"`
-	OriginalID         string     `json:"original_id" db:"original_id"`
-	UUID               uuid.UUID  `json:"uuid" db:"uuid"`
-	Name               string     `json:"name" db:"name"`
-	Notes              string     `json:"notes" db:"notes"`
-	GroupID            string     `json:"group_id" db:"group_id"`
-	ResourceCreatedAt  time.Time  `json:"resource_created_at" db:"resource_created_at"`
-	ResourceModifiedAt time.Time  `json:"resource_modified_at" db:"resource_modified_at"`
-	IPAddress          string     `json:"ip_address" db:"ip_address"`
-	Location           string     `json:"location" db:"location"`
-	IsConn             bool       `json:"is_conn" db:"is_conn"`
-	IsOn               bool       `json:"is_on" db:"is_on"`
-	Tags               Tags       `many_to_many:"resources_tags"`
-	Attributes         Attributes `has_many:"attributes"`
-	Providers          Providers  `many_to_many:"providers_resources"`
-	Incidents          Incidents  `many_to_many:"incidents_resources"`
+	ID                 uuid.UUID        `json:"id" db:"id"`
+	CreatedAt          time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at" db:"updated_at"`
+	Provider           string           `json:"provider" db:"provider"`
+	Type               string           `json:"type" db:"type"`
+	OriginalID         string           `json:"original_id" db:"original_id"`
+	UUID               uuid.UUID        `json:"uuid" db:"uuid"`
+	Name               string           `json:"name" db:"name"`
+	Notes              string           `json:"notes" db:"notes"`
+	GroupID            string           `json:"group_id" db:"group_id"`
+	ResourceCreatedAt  time.Time        `json:"resource_created_at" db:"resource_created_at"`
+	ResourceModifiedAt time.Time        `json:"resource_modified_at" db:"resource_modified_at"`
+	IPAddress          string           `json:"ip_address" db:"ip_address"`
+	Location           string           `json:"location" db:"location"`
+	IsConn             bool             `json:"is_conn" db:"is_conn"`
+	IsOn               bool             `json:"is_on" db:"is_on"`
+	State              string           `json:"state" db:"state"`
+	StateChangedAt     time.Time        `json:"state_changed_at" db:"state_changed_at"`
+	Tags               Tags             `many_to_many:"resources_tags"`
+	Attributes         Attributes       `has_many:"attributes"`
+	AttributeChanges   AttributeChanges `has_many:"attribute_changes" order_by:"created_at desc"`
+	Providers          Providers        `many_to_many:"providers_resources"`
+	Incidents          Incidents        `many_to_many:"incidents_resources"`
 }
 
 // ResourcesTags is struct for mapping resources to tags.
@@ -72,6 +85,11 @@ func (r Resource) JSON() string {
 	return string(jr)
 }
 
+// IsActive returns true if the resource still exists on the provider.
+func (r Resource) IsActive() bool {
+	return r.State == ResourceActive || r.State == ""
+}
+
 // Resources is an array of resources
 type Resources []Resource
 
@@ -116,24 +134,58 @@ func (r *Resource) Services() *Services {
 	return services
 }
 
-// AddAttribute creates and saves attribute of the resource.
-func (r *Resource) AddAttribute(name, value string) error {
-	attr := &Attribute{
-		ResourceID: r.ID,
-		Name:       name,
-		Value:      value,
+// SetAttribute creates or updates the attribute of the resource and records
+// the change on the attribute history with the sync run caused it. The run
+// could be uuid.Nil for manual changes.
+func (r *Resource) SetAttribute(tx *pop.Connection, runID uuid.UUID, name string, value AttributeValue) error {
+	attr := &Attribute{}
+	old := ""
+	if err := tx.Where("resource_id = ? AND name = ?", r.ID, name).First(attr); err == nil {
+		if attr.Value == value.Value && attr.Type == value.Type {
+			return nil
+		}
+		old = attr.Value
+	} else {
+		attr = &Attribute{ResourceID: r.ID, Name: name}
+	}
+	attr.SetValue(value)
+	verrs, err := tx.ValidateAndSave(attr)
+	if err != nil {
+		return err
 	}
-	verrs, err := DB.ValidateAndCreate(attr)
 	if verrs.HasAny() {
 		return errors.New("validation error")
 	}
-	return err
+	if old == value.Value { // type only
+		return nil
+	}
+	return r.recordAttributeChange(tx, runID, name, old, value.Value)
+}
+
+// UnsetAttribute removes the attribute and records the change.
+func (r *Resource) UnsetAttribute(tx *pop.Connection, runID uuid.UUID, attr *Attribute) error {
+	if err := tx.Destroy(attr); err != nil {
+		return err
+	}
+	return r.recordAttributeChange(tx, runID, attr.Name, attr.Value, "")
+}
+
+func (r *Resource) recordAttributeChange(tx *pop.Connection, runID uuid.UUID, name, old, new string) error {
+	return tx.Create(&AttributeChange{
+		ResourceID: r.ID,
+		SyncRunID:  runID,
+		Name:       name,
+		OldValue:   old,
+		NewValue:   new,
+	})
 }
 
 // LinkTags makes a link map of resource and user.
 // Since this models are mirrored from its origin, this function should
 // handle duplications and updates by checking existing one.
-func (r *Resource) LinkTags(ts []string) error {
+// It stops on the first tag could not be created so the transaction of
+// the caller could be rolled back instead of saving a broken map.
+func (r *Resource) LinkTags(tx *pop.Connection, ts []string) error {
 	requestedTags, err := utils.ToInterface(utils.Cleaner(ts))
 	if err != nil {
 		return errors.New("could not convert argument to interface slice")
@@ -143,14 +195,14 @@ func (r *Resource) LinkTags(ts []string) error {
 	hasError := false
 	// get existing tag map and remove them from given list.
 	existingMaps := &RTMaps{}
-	if err := DB.Where("resource_id = ?", r.ID).All(existingMaps); err != nil {
+	if err := tx.Where("resource_id = ?", r.ID).All(existingMaps); err != nil {
 		mlogger.Errorf("database selection failed! error: %v", err)
 	}
 	for _, m := range *existingMaps {
 		existingTag := &Tag{}
-		err := DB.Find(existingTag, m.TagID)
+		err := tx.Find(existingTag, m.TagID)
 		if err != nil { // in case of broken link map, but why? safety?
-			err := DB.Destroy(&m)
+			err := tx.Destroy(&m)
 			if err != nil {
 				mlogger.Errorf("found broken map but could not delete: id:%v", m.ID)
 			} else {
@@ -162,7 +214,7 @@ func (r *Resource) LinkTags(ts []string) error {
 			requestedTags = utils.Remove(requestedTags, existingTag.Name)
 		} else {
 			mlogger.Debugf("removing %v from map. no longer exists", existingTag.Name)
-			if err := DB.Destroy(&m); err != nil {
+			if err := tx.Destroy(&m); err != nil {
 				mlogger.Errorf("could not remove  %v from the map", m)
 				hasError = true
 			}
@@ -175,15 +227,17 @@ func (r *Resource) LinkTags(ts []string) error {
 
 		// search existing tag entry or create new one.
 		tag := &Tag{}
-		if err := DB.Where("name = ?", name).First(tag); err != nil { // if none
+		if err := tx.Where("name = ?", name).First(tag); err != nil { // if none
 			mlogger.Debugf("create new tag %v...", name)
 			tag.Name = name
-			verrs, err := DB.ValidateAndCreate(tag)
-			if verrs.HasAny() {
-				mlogger.Errorf("could not save resource-tag map %v: %v", tag, verrs)
-			}
+			verrs, err := tx.ValidateAndCreate(tag)
 			if err != nil {
-				mlogger.Errorf("could not save resource-tag map %v: %v", tag, err)
+				mlogger.Errorf("could not create tag %v: %v", name, err)
+				return err
+			}
+			if verrs.HasAny() {
+				mlogger.Errorf("could not create tag %v: %v", name, verrs)
+				return errors.New("validation error")
 			}
 		}
 
@@ -192,7 +246,7 @@ func (r *Resource) LinkTags(ts []string) error {
 			ResourceID: r.ID,
 			TagID:      tag.ID,
 		}
-		if err := DB.Save(rtmap); err != nil {
+		if err := tx.Save(rtmap); err != nil {
 			mlogger.Errorf("could not save resource-tag map %v: %v", rtmap, err)
 			hasError = true
 		}
@@ -205,7 +259,7 @@ func (r *Resource) LinkTags(ts []string) error {
 
 // LinkUsers makes a link map of resource and user.
 // the logic is same as `LinkTags()`.
-func (r *Resource) LinkUsers(us []string) error {
+func (r *Resource) LinkUsers(tx *pop.Connection, us []string) error {
 	requestedUsers, err := utils.ToInterface(us)
 	if err != nil {
 		return errors.New("could not convert argument to interface slice")
@@ -215,7 +269,7 @@ func (r *Resource) LinkUsers(us []string) error {
 	hasError := false
 	// get existing user map and remove them from given list
 	maps := &RUMaps{}
-	if err := DB.Where("resource_id = ?", r.ID).All(maps); err != nil {
+	if err := tx.Where("resource_id = ?", r.ID).All(maps); err != nil {
 		mlogger.Errorf("database selection failed! error: %v", err)
 	}
 	for _, m := range *maps {
@@ -223,7 +277,7 @@ func (r *Resource) LinkUsers(us []string) error {
 			requestedUsers = utils.Remove(requestedUsers, m.UserID)
 		} else {
 			mlogger.Debugf("removing %v from map. no longer exists", m.UserID)
-			if err := DB.Destroy(&m); err != nil {
+			if err := tx.Destroy(&m); err != nil {
 				mlogger.Errorf("could not remove user %v from the map", m)
 				hasError = true
 			}
@@ -237,7 +291,7 @@ func (r *Resource) LinkUsers(us []string) error {
 			ResourceID: r.ID,
 			UserID:     u.(string), //! check me
 		}
-		if err := DB.Save(rumap); err != nil {
+		if err := tx.Save(rumap); err != nil {
 			mlogger.Errorf("could not save resource-user map %v: %v", rumap, err)
 			hasError = true
 		}
@@ -248,13 +302,238 @@ func (r *Resource) LinkUsers(us []string) error {
 	return nil
 }
 
+// SyncAttributes makes attributes of the resource same as given map.
+// Only changed attributes are updated, and attributes no longer exist are
+// removed. All changes are recorded on the history with the sync run.
+func (r *Resource) SyncAttributes(tx *pop.Connection, runID uuid.UUID, attrs AttributeValues) error {
+	existing := &Attributes{}
+	if err := tx.Where("resource_id = ?", r.ID).All(existing); err != nil {
+		mlogger.Errorf("database selection failed! error: %v", err)
+		return err
+	}
+
+	hasError := false
+	for _, a := range *existing {
+		if attrs[a.Name].Value == "" {
+			if err := r.UnsetAttribute(tx, runID, &a); err != nil {
+				mlogger.Errorf("could not remove attribute %v: %v", a, err)
+				hasError = true
+			}
+		}
+	}
+	for name, value := range attrs {
+		if value.Value == "" {
+			continue
+		}
+		if err := r.SetAttribute(tx, runID, name, value); err != nil {
+			mlogger.Errorf("could not set attribute %v:%v: %v", name, value.Value, err)
+			hasError = true
+		}
+	}
+	if hasError {
+		return errors.New("syncing done with error(s)")
+	}
+	return nil
+}
+
+//*** change detection
+
+// Existing returns stored resource which is the same as the resource with
+// its tags and attributes. Resources without UUID are identified by their
+// origin. It returns nil if there is no such resource.
+func (r *Resource) Existing(tx *pop.Connection) *Resource {
+	existing := &Resource{}
+	if r.ID != uuid.Nil {
+		if err := tx.Eager("Tags", "Attributes").Find(existing, r.ID); err != nil {
+			return nil
+		}
+		return existing
+	}
+	query := tx.Eager("Tags", "Attributes").
+		Where("provider = ? AND type = ? AND original_id = ?", r.Provider, r.Type, r.OriginalID)
+	if err := query.First(existing); err != nil {
+		return nil
+	}
+	return existing
+}
+
+// ResourceDiff is a difference between stored resource and incoming one.
+type ResourceDiff struct {
+	Fields     []string
+	Tags       bool
+	Attributes bool
+	Details    []string
+}
+
+// IsEmpty returns true if nothing was changed.
+func (d ResourceDiff) IsEmpty() bool {
+	return len(d.Fields) == 0 && !d.Tags && !d.Attributes
+}
+
+// Diff compares the stored resource with incoming resource and its tags
+// and attributes. The stored resource should be loaded with its tags and
+// attributes.
+func (r *Resource) Diff(o *Resource, tags []string, attrs AttributeValues) ResourceDiff {
+	diff := ResourceDiff{}
+	field := func(name string, changed bool, old, new interface{}) {
+		if changed {
+			diff.Fields = append(diff.Fields, name)
+			diff.Details = append(diff.Details, fmt.Sprintf("%v: %v -> %v", name, old, new))
+		}
+	}
+	sameTime := func(a, b time.Time) bool {
+		return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+	}
+	field("type", r.Type != o.Type, r.Type, o.Type)
+	field("original_id", r.OriginalID != o.OriginalID, r.OriginalID, o.OriginalID)
+	field("uuid", r.UUID != o.UUID, r.UUID, o.UUID)
+	field("name", r.Name != o.Name, r.Name, o.Name)
+	field("notes", r.Notes != o.Notes, r.Notes, o.Notes)
+	field("group_id", r.GroupID != o.GroupID, r.GroupID, o.GroupID)
+	field("resource_created_at", !sameTime(r.ResourceCreatedAt, o.ResourceCreatedAt), r.ResourceCreatedAt, o.ResourceCreatedAt)
+	field("resource_modified_at", !sameTime(r.ResourceModifiedAt, o.ResourceModifiedAt), r.ResourceModifiedAt, o.ResourceModifiedAt)
+	field("ip_address", r.IPAddress != o.IPAddress, r.IPAddress, o.IPAddress)
+	field("location", r.Location != o.Location, r.Location, o.Location)
+	field("is_conn", r.IsConn != o.IsConn, r.IsConn, o.IsConn)
+	field("is_on", r.IsOn != o.IsOn, r.IsOn, o.IsOn)
+	field("state", r.State != o.State, r.State, o.State)
+
+	oldTags := []string{}
+	for _, t := range r.Tags {
+		oldTags = append(oldTags, t.Name)
+	}
+	newTags := utils.Cleaner(tags)
+	sort.Strings(oldTags)
+	sort.Strings(newTags)
+	if strings.Join(oldTags, ",") != strings.Join(newTags, ",") {
+		diff.Tags = true
+		diff.Details = append(diff.Details, fmt.Sprintf("tags: %v -> %v", oldTags, newTags))
+	}
+
+	oldAttrs := map[string]Attribute{}
+	for _, a := range r.Attributes {
+		if _, ok := oldAttrs[a.Name]; ok { // duplicated by old sync
+			diff.Attributes = true
+		}
+		oldAttrs[a.Name] = a
+	}
+	for name, value := range attrs {
+		if value.Value == "" {
+			continue
+		}
+		if old, ok := oldAttrs[name]; !ok {
+			diff.Details = append(diff.Details, fmt.Sprintf("attribute %v: added %v", name, value.Value))
+		} else if old.Value != value.Value {
+			diff.Details = append(diff.Details, fmt.Sprintf("attribute %v: %v -> %v", name, old.Value, value.Value))
+		} else if old.Type != value.Type { // stored before typed attributes
+			diff.Details = append(diff.Details, fmt.Sprintf("attribute %v: typed as %v", name, value.Type))
+		} else {
+			continue
+		}
+		diff.Attributes = true
+	}
+	for name, old := range oldAttrs {
+		if attrs[name].Value == "" {
+			diff.Attributes = true
+			diff.Details = append(diff.Details, fmt.Sprintf("attribute %v: removed %v", name, old.Value))
+		}
+	}
+	return diff
+}
+
+//*** lifecycle
+
+// MarkMissing marks the resource as missing if it is active.
+func (r *Resource) MarkMissing(tx *pop.Connection) error {
+	if !r.IsActive() {
+		return nil
+	}
+	r.State = ResourceMissing
+	r.StateChangedAt = time.Now()
+	return tx.UpdateColumns(r, "state", "state_changed_at", "updated_at")
+}
+
+// RetireResources retires resources missing longer than grace period.
+// It returns the number of retired resources.
+func RetireResources(grace time.Duration) (int, error) {
+	resources := &Resources{}
+	query := DB.Where("state = ? AND state_changed_at < ?", ResourceMissing, time.Now().Add(-grace))
+	if err := query.All(resources); err != nil {
+		return 0, err
+	}
+	for _, r := range *resources {
+		r.State = ResourceRetired
+		r.StateChangedAt = time.Now()
+		if err := DB.UpdateColumns(&r, "state", "state_changed_at", "updated_at"); err != nil {
+			return 0, err
+		}
+		mlogger.Infof("resource %v was retired", r)
+	}
+	return len(*resources), nil
+}
+
+// PurgeResources removes resources retired longer than given duration
+// with their tag, user, provider and incident links and attributes.
+// It returns the number of purged resources.
+func PurgeResources(after time.Duration) (int, error) {
+	resources := &Resources{}
+	query := DB.Where("state = ? AND state_changed_at < ?", ResourceRetired, time.Now().Add(-after))
+	if err := query.All(resources); err != nil {
+		return 0, err
+	}
+	for _, r := range *resources {
+		if err := r.Purge(); err != nil {
+			return 0, err
+		}
+		mlogger.Infof("resource %v was purged", r)
+	}
+	return len(*resources), nil
+}
+
+// Purge removes the resource and all of its links and attributes.
+func (r *Resource) Purge() error {
+	return DB.Transaction(func(tx *pop.Connection) error {
+		for _, table := range []string{
+			"attributes",
+			"attribute_changes",
+			"resources_tags",
+			"resources_users",
+			"providers_resources",
+			"incidents_resources",
+		} {
+			if err := tx.RawQuery("DELETE FROM "+table+" WHERE resource_id = ?", r.ID).Exec(); err != nil {
+				return err
+			}
+		}
+		return tx.Destroy(r)
+	})
+}
+
 //*** common database functions and methods
 
-// Save stores the resource
-//! be smart!
-func (r *Resource) Save() error {
-	if err := DB.Create(r); err != nil {
-		return DB.Update(r)
+// Save stores the resource. It updates the resource if it already exists
+// instead of trying creation first, since a failed statement aborts whole
+// transaction.
+func (r *Resource) Save(tx *pop.Connection) error {
+	if r.ID != uuid.Nil {
+		exists, err := tx.Where("id = ?", r.ID).Exists(&Resource{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return tx.Update(r)
+		}
+	}
+	return tx.Create(r)
+}
+
+// BeforeCreate sets initial state of new resource.
+func (r *Resource) BeforeCreate(tx *pop.Connection) error {
+	if r.State == "" {
+		r.State = ResourceActive
+	}
+	if r.StateChangedAt.IsZero() {
+		r.StateChangedAt = time.Now()
 	}
 	return nil
 }
@@ -0,0 +1,118 @@
+package models_test
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+// resource creates a resource in the state changed at given time ago.
+func (ms *ModelSuite) resource(name, state string, ago time.Duration) *models.Resource {
+	r := &models.Resource{Provider: "fake", Type: "vm", Name: name, OriginalID: name}
+	ms.NoError(ms.DB.Create(r))
+	ms.NoError(ms.DB.RawQuery("UPDATE resources SET state = ?, state_changed_at = ? WHERE id = ?",
+		state, time.Now().Add(-ago), r.ID).Exec())
+	ms.NoError(ms.DB.Reload(r))
+	return r
+}
+
+// stateOf returns the stored state of the resource, or empty if purged.
+func (ms *ModelSuite) stateOf(r *models.Resource) string {
+	stored := &models.Resource{}
+	if err := ms.DB.Find(stored, r.ID); err != nil {
+		return ""
+	}
+	return stored.State
+}
+
+func (ms *ModelSuite) Test_Resource_MarkMissing() {
+	r := ms.resource("web-01", models.ResourceActive, time.Hour)
+	ms.NoError(r.MarkMissing(ms.DB))
+	ms.Equal(models.ResourceMissing, ms.stateOf(r))
+	ms.WithinDuration(time.Now(), r.StateChangedAt, time.Minute)
+
+	// missing or retired resources keep the time of their state
+	for _, state := range []string{models.ResourceMissing, models.ResourceRetired} {
+		r = ms.resource("web-"+state, state, time.Hour)
+		changedAt := r.StateChangedAt
+		ms.NoError(r.MarkMissing(ms.DB))
+		ms.Equal(state, ms.stateOf(r))
+		ms.True(changedAt.Equal(r.StateChangedAt))
+	}
+}
+
+func (ms *ModelSuite) Test_RetireResources() {
+	old := ms.resource("old", models.ResourceMissing, 73*time.Hour)
+	recent := ms.resource("recent", models.ResourceMissing, time.Hour)
+	active := ms.resource("active", models.ResourceActive, 100*time.Hour)
+
+	n, err := models.RetireResources(72 * time.Hour)
+	ms.NoError(err)
+	ms.Equal(1, n)
+	ms.Equal(models.ResourceRetired, ms.stateOf(old))
+	ms.Equal(models.ResourceMissing, ms.stateOf(recent))
+	ms.Equal(models.ResourceActive, ms.stateOf(active))
+
+	n, err = models.RetireResources(72 * time.Hour)
+	ms.NoError(err)
+	ms.Equal(0, n)
+}
+
+func (ms *ModelSuite) Test_PurgeResources() {
+	old := ms.resource("old", models.ResourceRetired, 721*time.Hour)
+	recent := ms.resource("recent", models.ResourceRetired, time.Hour)
+	missing := ms.resource("missing", models.ResourceMissing, 1000*time.Hour)
+
+	tag := &models.Tag{Name: "web"}
+	ms.NoError(ms.DB.Create(tag))
+	for _, r := range []*models.Resource{old, recent} {
+		ms.NoError(r.LinkTags(ms.DB, []string{"web"}))
+		ms.NoError(r.SetAttribute(ms.DB, uuid.Nil, "cpu", models.IntegerValue(4)))
+	}
+
+	n, err := models.PurgeResources(720 * time.Hour)
+	ms.NoError(err)
+	ms.Equal(1, n)
+	ms.Equal("", ms.stateOf(old))
+	ms.Equal(models.ResourceRetired, ms.stateOf(recent))
+	ms.Equal(models.ResourceMissing, ms.stateOf(missing))
+
+	for _, table := range []interface{}{&models.Attribute{}, &models.AttributeChange{}, &models.ResourcesTags{}} {
+		count, err := ms.DB.Where("resource_id = ?", old.ID).Count(table)
+		ms.NoError(err)
+		ms.Equal(0, count)
+		count, err = ms.DB.Where("resource_id = ?", recent.ID).Count(table)
+		ms.NoError(err)
+		ms.Equal(1, count)
+	}
+	count, err := ms.DB.Count(&models.Tag{})
+	ms.NoError(err)
+	ms.Equal(1, count, "tags are kept")
+}
+
+func (ms *ModelSuite) Test_Service_TaggedResources() {
+	tag := &models.Tag{Name: "web"}
+	ms.NoError(ms.DB.Create(tag))
+	resources := map[string]*models.Resource{}
+	for _, state := range []string{models.ResourceActive, models.ResourceMissing, models.ResourceRetired} {
+		resources[state] = ms.resource("web-"+state, state, time.Hour)
+		ms.NoError(resources[state].LinkTags(ms.DB, []string{"web"}))
+	}
+	ms.resource("untagged", models.ResourceActive, time.Hour)
+
+	service := &models.Service{Name: "shop", Tags: models.Tags{*tag}}
+	found := service.TaggedResources()
+	ms.Len(*found, 1)
+	ms.Equal(resources[models.ResourceActive].ID, (*found)[0].ID)
+
+	found = service.TaggedResources(models.ResourceActive, models.ResourceMissing)
+	ms.Len(*found, 2)
+	for _, r := range *found {
+		ms.NotEqual(models.ResourceRetired, r.State)
+	}
+
+	ms.Len(*service.TaggedResources(models.ResourceRetired), 1)
+	ms.Len(*(&models.Service{Name: "empty"}).TaggedResources(), 0)
+}
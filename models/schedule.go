@@ -0,0 +1,145 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+
+	"github.com/hyeoncheon/honcheonui/utils"
+)
+
+// Schedule is a cron schedule of a periodic worker. A schedule without
+// provider overrides the default schedule of the worker, and a schedule
+// with provider overrides it for the provider only. Paused default schedule
+// pauses the worker including its overrides.
+type Schedule struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	Worker     string    `json:"worker" db:"worker"`
+	ProviderID uuid.UUID `json:"provider_id" db:"provider_id"`
+	Cron       string    `json:"cron" db:"cron"`
+	Paused     bool      `json:"paused" db:"paused"`
+	Provider   *Provider `json:"-" db:"-"`
+}
+
+// Schedules is an array of schedules
+type Schedules []Schedule
+
+// String returns the cron expression of the schedule
+func (s Schedule) String() string {
+	return s.Cron
+}
+
+// IsOverride returns true if the schedule is for a provider.
+func (s Schedule) IsOverride() bool {
+	return s.ProviderID != uuid.Nil
+}
+
+// Next returns the next run time of the schedule after given time.
+func (s Schedule) Next(t time.Time) (time.Time, error) {
+	c, err := utils.ParseCron(s.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.Next(t), nil
+}
+
+// AllSchedules returns all schedules with providers of overrides.
+func AllSchedules() (*Schedules, error) {
+	schedules := &Schedules{}
+	if err := DB.Order("worker, created_at").All(schedules); err != nil {
+		return schedules, err
+	}
+	for i, s := range *schedules {
+		if !s.IsOverride() {
+			continue
+		}
+		provider := &Provider{}
+		if err := DB.Find(provider, s.ProviderID); err == nil {
+			(*schedules)[i].Provider = provider
+		}
+	}
+	return schedules, nil
+}
+
+// For returns the schedule of the worker for the provider. It returns the
+// default schedule of the worker if there is no override for the provider,
+// or nil if there is no schedule at all. Use uuid.Nil to get the default.
+func (s Schedules) For(worker string, providerID uuid.UUID) *Schedule {
+	var def *Schedule
+	for i, e := range s {
+		if e.Worker != worker {
+			continue
+		}
+		if e.ProviderID == providerID {
+			return &s[i]
+		}
+		if !e.IsOverride() {
+			def = &s[i]
+		}
+	}
+	return def
+}
+
+// Default returns the default schedule of the worker stored on database,
+// or nil if the worker uses its built-in schedule.
+func (s Schedules) Default(worker string) *Schedule {
+	return s.For(worker, uuid.Nil)
+}
+
+// IsPaused returns true if the worker is paused.
+func (s Schedules) IsPaused(worker string) bool {
+	def := s.Default(worker)
+	return def != nil && def.Paused
+}
+
+// Overrides returns schedules of the worker for specific providers.
+func (s Schedules) Overrides(worker string) Schedules {
+	overrides := Schedules{}
+	for _, e := range s {
+		if e.Worker == worker && e.IsOverride() {
+			overrides = append(overrides, e)
+		}
+	}
+	return overrides
+}
+
+//*** validators
+
+// Validate gets run every time you call a "pop.Validate*" method.
+func (s *Schedule) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: s.Worker, Name: "Worker"},
+		&validators.FuncValidator{
+			Field:   s.Cron,
+			Name:    "Cron",
+			Message: "%v is not a valid cron expression",
+			Fn: func() bool {
+				_, err := utils.ParseCron(s.Cron)
+				return err == nil
+			},
+		},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (s *Schedule) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	verrs := validate.NewErrors()
+	exists, err := tx.Where("worker = ? AND provider_id = ?", s.Worker, s.ProviderID).Exists(&Schedule{})
+	if err != nil {
+		return verrs, err
+	}
+	if exists {
+		verrs.Add("worker", "schedule already exists for the worker and provider")
+	}
+	return verrs, nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (s *Schedule) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
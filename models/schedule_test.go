@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Schedules(t *testing.T) {
+	r := require.New(t)
+	p1 := uuid.Must(uuid.NewV4())
+	p2 := uuid.Must(uuid.NewV4())
+
+	schedules := Schedules{
+		{Worker: "worker.ResourceSync", Cron: "0 3 * * *"},
+		{Worker: "worker.ResourceSync", ProviderID: p1, Cron: "@hourly"},
+		{Worker: "worker.NotificationWatch", ProviderID: p2, Cron: "*/10 * * * *"},
+	}
+	r.Equal("0 3 * * *", schedules.Default("worker.ResourceSync").Cron)
+	r.Nil(schedules.Default("worker.NotificationWatch"))
+	r.Equal("@hourly", schedules.For("worker.ResourceSync", p1).Cron)
+	r.Equal("0 3 * * *", schedules.For("worker.ResourceSync", p2).Cron)
+	r.Nil(schedules.For("worker.NotificationWatch", p1))
+	r.Len(schedules.Overrides("worker.ResourceSync"), 1)
+	r.True(schedules.Overrides("worker.ResourceSync")[0].IsOverride())
+
+	next, err := schedules[0].Next(time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC))
+	r.NoError(err)
+	r.Equal(time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC), next)
+
+	_, err = Schedule{Cron: "daily"}.Next(time.Now())
+	r.Error(err)
+}
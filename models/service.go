@@ -97,20 +97,29 @@ func (s *Service) LinkTags(tagIDs []string) error {
 
 // TaggedResources gets and returns all accessible tags.
 // Service has associated resources but the relationship is indirect via tags.
-func (s *Service) TaggedResources() *Resources {
+// Only active resources are returned unless lifecycle states are given.
+func (s *Service) TaggedResources(states ...string) *Resources {
 	resources := &Resources{}
 	if len(s.Tags) < 1 {
 		return resources
 	}
+	if len(states) < 1 {
+		states = []string{ResourceActive}
+	}
 
 	var IDs []interface{}
 	for _, t := range s.Tags {
 		IDs = append(IDs, t.ID)
 	}
+	var stateArgs []interface{}
+	for _, st := range states {
+		stateArgs = append(stateArgs, st)
+	}
 
 	query := DB.Q().
 		Join("resources_tags", "resources_tags.resource_id = resources.id").
 		Where("resources_tags.tag_id in (?)", IDs...).
+		Where("resources.state in (?)", stateArgs...).
 		GroupBy("resources.id")
 	// if matching rule is MatchAll, resources which has all tags will be
 	// selected. Otherwise resources with any tags are selected.
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// kinds of sync runs
+const (
+	SyncResource     = "resource"
+	SyncNotification = "notification"
+)
+
+// SyncRun is a record of a sync run for a provider. It is created when the
+// run is started and updated with its result when the run is finished.
+type SyncRun struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ProviderID  uuid.UUID `json:"provider_id" db:"provider_id"`
+	Kind        string    `json:"kind" db:"kind"`
+	StartedAt   time.Time `json:"started_at" db:"started_at"`
+	FinishedAt  time.Time `json:"finished_at" db:"finished_at"`
+	Total       int       `json:"total" db:"total"`
+	Added       int       `json:"added" db:"added"`
+	Modified    int       `json:"modified" db:"modified"`
+	Removed     int       `json:"removed" db:"removed"`
+	Error       string    `json:"error" db:"error"`
+	ChangeSetID uuid.UUID `json:"change_set_id" db:"change_set_id"`
+	Provider    Provider  `json:"-" belongs_to:"provider"`
+}
+
+// String returns kind and provider of the run
+func (s SyncRun) String() string {
+	return s.Kind + " sync for " + s.Provider.String()
+}
+
+// IsRunning returns true if the run is not finished yet.
+func (s SyncRun) IsRunning() bool {
+	return s.FinishedAt.IsZero()
+}
+
+// IsFailed returns true if the run was finished with error.
+func (s SyncRun) IsFailed() bool {
+	return s.Error != ""
+}
+
+// Status returns running, failed, or succeeded.
+func (s SyncRun) Status() string {
+	switch {
+	case s.IsRunning():
+		return "running"
+	case s.IsFailed():
+		return "failed"
+	default:
+		return "succeeded"
+	}
+}
+
+// HasChangeSet returns true if the run recorded a change set.
+func (s SyncRun) HasChangeSet() bool {
+	return s.ChangeSetID != uuid.Nil
+}
+
+// Duration returns elapsed time of the run.
+func (s SyncRun) Duration() time.Duration {
+	if s.IsRunning() {
+		return time.Since(s.StartedAt).Truncate(time.Second)
+	}
+	return s.FinishedAt.Sub(s.StartedAt).Truncate(time.Millisecond)
+}
+
+// SyncRuns is an array of sync runs
+type SyncRuns []SyncRun
+
+// StartSyncRun creates and returns new sync run for the provider.
+func StartSyncRun(providerID uuid.UUID, kind string) *SyncRun {
+	run := &SyncRun{
+		ProviderID: providerID,
+		Kind:       kind,
+		StartedAt:  time.Now(),
+	}
+	if err := DB.Create(run); err != nil {
+		mlogger.Errorf("could not create sync run %v: %v", kind, err)
+	}
+	return run
+}
+
+// Finish records the result of the run.
+func (s *SyncRun) Finish(err error) error {
+	s.FinishedAt = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	return DB.Save(s)
+}
+
+// Count records counts from the change set.
+func (s *SyncRun) Count(c *ChangeSet) {
+	s.Added = c.Added
+	s.Modified = c.Modified
+	s.Removed = c.Removed
+	s.Total = c.Added + c.Modified + c.Unchanged
+	s.ChangeSetID = c.ID
+}
+
+// RecentSyncRuns returns recent sync runs of all providers.
+func RecentSyncRuns(limit int) *SyncRuns {
+	runs := &SyncRuns{}
+	if err := DB.Eager("Provider").Order("started_at desc").Limit(limit).All(runs); err != nil {
+		mlogger.Errorf("could not get recent sync runs: %v", err)
+	}
+	return runs
+}
+
+// LastSyncRun returns the last sync run of the kind for the provider.
+// It returns nil if the provider was never synced.
+func (p Provider) LastSyncRun(kind string) *SyncRun {
+	run := &SyncRun{}
+	query := DB.Where("provider_id = ? AND kind = ?", p.ID, kind).Order("started_at desc")
+	if err := query.First(run); err != nil {
+		return nil
+	}
+	return run
+}
+
+// LastSyncRuns returns the last sync runs of each kind for the provider.
+func (p Provider) LastSyncRuns() SyncRuns {
+	runs := SyncRuns{}
+	for _, kind := range []string{SyncResource, SyncNotification} {
+		if run := p.LastSyncRun(kind); run != nil {
+			runs = append(runs, *run)
+		}
+	}
+	return runs
+}
+
+//*** validators
+
+// Validate gets run every time you call a "pop.Validate*" method.
+func (s *SyncRun) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: s.ProviderID, Name: "ProviderID"},
+		&validators.StringInclusion{Field: s.Kind, Name: "Kind", List: []string{SyncResource, SyncNotification}},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (s *SyncRun) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (s *SyncRun) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SyncRun_Status(t *testing.T) {
+	r := require.New(t)
+	run := SyncRun{Kind: SyncResource, StartedAt: time.Now().Add(-time.Minute)}
+	r.True(run.IsRunning())
+	r.Equal("running", run.Status())
+	r.True(run.Duration() >= time.Minute)
+
+	run.FinishedAt = run.StartedAt.Add(1500 * time.Millisecond)
+	r.Equal("succeeded", run.Status())
+	r.Equal(1500*time.Millisecond, run.Duration())
+
+	run.Error = errors.New("authentication failed").Error()
+	r.True(run.IsFailed())
+	r.Equal("failed", run.Status())
+}
+
+func Test_SyncRun_Count(t *testing.T) {
+	r := require.New(t)
+	cs := &ChangeSet{Added: 1, Modified: 2, Removed: 3, Unchanged: 4}
+	run := &SyncRun{}
+	r.False(run.HasChangeSet())
+
+	run.Count(cs)
+	r.Equal(7, run.Total)
+	r.Equal(1, run.Added)
+	r.Equal(2, run.Modified)
+	r.Equal(3, run.Removed)
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// WorkerRun is the run statistics of a worker. It is updated by any
+// instance when a run of the worker is finished so all instances show the
+// same last run.
+type WorkerRun struct {
+	ID           uuid.UUID     `json:"id" db:"id"`
+	CreatedAt    time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at" db:"updated_at"`
+	Worker       string        `json:"worker" db:"worker"`
+	CountRun     int           `json:"count_run" db:"count_run"`
+	LastRunAt    time.Time     `json:"last_run_at" db:"last_run_at"`
+	LastDuration time.Duration `json:"last_duration" db:"last_duration"`
+	LastError    string        `json:"last_error" db:"last_error"`
+}
+
+// WorkerRuns is an array of worker runs
+type WorkerRuns []WorkerRun
+
+// String returns the name of the worker
+func (w WorkerRun) String() string {
+	return w.Worker
+}
+
+// RecordWorkerRun records the finished run of the worker. The count is
+// increased on the database since instances could finish runs at the same
+// time.
+func RecordWorkerRun(name string, startedAt time.Time, duration time.Duration, runErr error) error {
+	run := &WorkerRun{
+		Worker:       name,
+		CountRun:     1,
+		LastRunAt:    startedAt,
+		LastDuration: duration,
+	}
+	if runErr != nil {
+		run.LastError = runErr.Error()
+	}
+	update := func() (int, error) {
+		return DB.RawQuery("UPDATE worker_runs SET count_run = count_run + 1,"+
+			" last_run_at = ?, last_duration = ?, last_error = ?, updated_at = ? WHERE worker = ?",
+			run.LastRunAt, int64(run.LastDuration), run.LastError, time.Now(), name).ExecWithCount()
+	}
+	if n, err := update(); err != nil || n > 0 {
+		return err
+	}
+	verrs, err := DB.ValidateAndCreate(run)
+	if err != nil { // created by another instance in the meantime
+		_, err = update()
+		return err
+	}
+	if verrs.HasAny() {
+		return verrs
+	}
+	return nil
+}
+
+// FindWorkerRun returns the run statistics of the worker, or nil if it was
+// never run.
+func FindWorkerRun(name string) *WorkerRun {
+	run := &WorkerRun{}
+	if err := DB.Where("worker = ?", name).First(run); err != nil {
+		return nil
+	}
+	return run
+}
+
+//*** validators
+
+// Validate gets run every time you call a "pop.Validate*" method.
+func (w *WorkerRun) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: w.Worker, Name: "Worker"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (w *WorkerRun) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (w *WorkerRun) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
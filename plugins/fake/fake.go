@@ -0,0 +1,151 @@
+// Package fake provides a fake provider for development and tests.
+// It serves accounts, resources, and notifications from a JSON fixture
+// file instead of real cloud accounts.
+//
+// The fixture could have edge cases such as duplicated UUIDs, resources
+// without tags, and malformed notifications. Malformed notifications are
+// returned as they are (as map[string]interface{}) so the host can handle
+// unrecognized data.
+package fake
+
+import (
+	_ "embed" // for default fixture
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/hyeoncheon/spec"
+)
+
+// constants for the fake provider
+const (
+	Name        = "fake"
+	Version     = "0.1.0"
+	SpecVersion = 1
+)
+
+//go:embed default.json
+var defaultFixture []byte
+
+// Account is an account of the fake provider.
+type Account struct {
+	User    string `json:"user"`
+	Pass    string `json:"pass"`
+	UserID  int    `json:"user_id"`
+	GroupID int    `json:"group_id"`
+}
+
+// Fixture is a structure of fixture file.
+type Fixture struct {
+	Accounts      []Account                 `json:"accounts"`
+	Resources     []spec.HoncheonuiResource `json:"resources"`
+	Notifications []json.RawMessage         `json:"notifications"`
+}
+
+// Provider is the fake provider implements spec.Provider.
+type Provider struct {
+	// Fixture is the path of fixture file. If it is empty, builtin default
+	// fixture is used.
+	Fixture string
+
+	once    sync.Once
+	fixture *Fixture
+	err     error
+}
+
+// New returns new fake provider for the fixture file.
+func New(fixture string) *Provider {
+	return &Provider{Fixture: fixture}
+}
+
+// Manifest implements plugins.Manifester
+func (p *Provider) Manifest() (string, string, int, []string) {
+	return Name, Version, SpecVersion, []string{"resources", "notifications"}
+}
+
+// CheckAccount implements spec.Provider
+func (p *Provider) CheckAccount(user, pass string) (int, int, error) {
+	account, err := p.account(user, pass)
+	if err != nil {
+		return 0, 0, err
+	}
+	return account.UserID, account.GroupID, nil
+}
+
+// GetResources implements spec.Provider
+func (p *Provider) GetResources(user, pass string) ([]interface{}, error) {
+	if _, err := p.account(user, pass); err != nil {
+		return nil, err
+	}
+	resources := []interface{}{}
+	for _, r := range p.fixture.Resources {
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
+// GetNotifications implements spec.Provider
+func (p *Provider) GetNotifications(user, pass string, since time.Time) ([]interface{}, error) {
+	if _, err := p.account(user, pass); err != nil {
+		return nil, err
+	}
+	notes := []interface{}{}
+	for _, raw := range p.fixture.Notifications {
+		note, err := parseNotification(raw)
+		if err != nil {
+			malformed := map[string]interface{}{}
+			json.Unmarshal(raw, &malformed)
+			notes = append(notes, malformed)
+			continue
+		}
+		if note.ModifiedAt.Before(since) {
+			continue
+		}
+		notes = append(notes, *note)
+	}
+	return notes, nil
+}
+
+//*** local functions
+
+func (p *Provider) load() {
+	data := defaultFixture
+	if p.Fixture != "" {
+		data, p.err = ioutil.ReadFile(p.Fixture)
+		if p.err != nil {
+			return
+		}
+	}
+	p.fixture = &Fixture{}
+	p.err = json.Unmarshal(data, p.fixture)
+}
+
+func (p *Provider) account(user, pass string) (*Account, error) {
+	p.once.Do(p.load)
+	if p.err != nil {
+		return nil, p.err
+	}
+	for _, a := range p.fixture.Accounts {
+		if a.User == user && a.Pass == pass {
+			return &a, nil
+		}
+	}
+	return nil, errors.New("authentication failed")
+}
+
+// parseNotification returns notification if the raw data is well-formed.
+func parseNotification(raw json.RawMessage) (*spec.HoncheonuiNotification, error) {
+	note := &spec.HoncheonuiNotification{}
+	if err := json.Unmarshal(raw, note); err != nil {
+		return nil, err
+	}
+	if note.OriginalID == "" || note.Title == "" || note.IssuedAt.IsZero() {
+		return nil, errors.New("required field is missing")
+	}
+	if note.ModifiedAt.IsZero() {
+		note.ModifiedAt = note.IssuedAt
+	}
+	return note, nil
+}
@@ -0,0 +1,91 @@
+package fake
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyeoncheon/spec"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Provider_Default(t *testing.T) {
+	r := require.New(t)
+	p := New("")
+
+	uid, gid, err := p.CheckAccount("fake", "fake")
+	r.NoError(err)
+	r.Equal(1001, uid)
+	r.Equal(100, gid)
+
+	_, _, err = p.CheckAccount("fake", "wrong")
+	r.Error(err)
+
+	resources, err := p.GetResources("fake", "fake")
+	r.NoError(err)
+	r.Len(resources, 4)
+
+	uuids := map[string]int{}
+	untagged := 0
+	for _, e := range resources {
+		res, ok := e.(spec.HoncheonuiResource)
+		r.True(ok)
+		uuids[res.UUID.String()]++
+		if len(res.Tags) == 0 {
+			untagged++
+		}
+	}
+	r.Len(uuids, 3, "fixture should have duplicated uuid")
+	r.Equal(1, untagged)
+
+	notes, err := p.GetNotifications("fake", "fake", time.Time{})
+	r.NoError(err)
+	r.Len(notes, 4)
+	malformed := 0
+	for _, n := range notes {
+		if _, ok := n.(spec.HoncheonuiNotification); !ok {
+			malformed++
+		}
+	}
+	r.Equal(2, malformed)
+
+	since := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	notes, err = p.GetNotifications("fake", "fake", since)
+	r.NoError(err)
+	r.Len(notes, 3)
+
+	_, err = p.GetResources("nobody", "fake")
+	r.Error(err)
+
+	name, _, specVersion, caps := p.Manifest()
+	r.Equal(Name, name)
+	r.Equal(SpecVersion, specVersion)
+	r.Contains(caps, "resources")
+}
+
+func Test_Provider_Fixture(t *testing.T) {
+	r := require.New(t)
+	dir, err := ioutil.TempDir("", "fake")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "fixture.json")
+	r.NoError(ioutil.WriteFile(path, []byte(`{
+		"accounts": [{"user": "u", "pass": "p", "user_id": 1, "group_id": 2}],
+		"resources": [{"Provider": "fake", "OriginalID": "1", "Name": "only"}]
+	}`), 0644))
+
+	p := New(path)
+	resources, err := p.GetResources("u", "p")
+	r.NoError(err)
+	r.Len(resources, 1)
+	notes, err := p.GetNotifications("u", "p", time.Time{})
+	r.NoError(err)
+	r.Len(notes, 0)
+
+	p = New(filepath.Join(dir, "missing.json"))
+	_, _, err = p.CheckAccount("u", "p")
+	r.Error(err)
+}
@@ -0,0 +1,31 @@
+// Command provider-fake is the fake provider plugin.
+//
+// Build it as a go plugin or as an out-of-process plugin:
+//
+//	go build -buildmode=plugin -o $HCU_HOME/plugins/provider-fake.so ./plugins/fake/provider
+//	go build -o $HCU_HOME/plugins/provider-fake ./plugins/fake/provider
+//
+// The fixture file is given by HCU_FAKE_FIXTURE environment variable.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/hyeoncheon/honcheonui/plugins"
+	"github.com/hyeoncheon/honcheonui/plugins/fake"
+)
+
+// Provider is the symbol looked up by the plugin registry. It should be
+// a value since plugin.Lookup returns the address of the variable.
+var Provider fake.Provider
+
+func init() {
+	Provider.Fixture = os.Getenv("HCU_FAKE_FIXTURE")
+}
+
+func main() {
+	if err := plugins.ServeProvider(&Provider); err != nil {
+		log.Fatal(err)
+	}
+}
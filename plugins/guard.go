@@ -0,0 +1,167 @@
+package plugins
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyeoncheon/spec"
+
+	"github.com/hyeoncheon/honcheonui/utils"
+)
+
+// constants
+const (
+	DefaultCallTimeout = 5 * time.Minute
+)
+
+// ErrTimeout is returned when a plugin call does not return in time.
+var ErrTimeout = errors.New("plugin call timed out")
+
+var limiters = map[string]*rateLimiter{}
+var limitersMutex sync.Mutex
+
+// rateLimiter keeps calls apart at least interval.
+type rateLimiter struct {
+	interval time.Duration
+	next     time.Time
+	mutex    sync.Mutex
+}
+
+// Wait blocks until the next call is allowed.
+func (l *rateLimiter) Wait() {
+	if l == nil || l.interval <= 0 {
+		return
+	}
+	l.mutex.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mutex.Unlock()
+
+	time.Sleep(wait)
+}
+
+// limiterFor returns shared rate limiter of the plugin. The interval is
+// configured by HCU_PLUGIN_RATE_LIMIT_<NAME> or HCU_PLUGIN_RATE_LIMIT.
+func limiterFor(name string) *rateLimiter {
+	limitersMutex.Lock()
+	defer limitersMutex.Unlock()
+	if l, ok := limiters[name]; ok {
+		return l
+	}
+	def := envDuration("HCU_PLUGIN_RATE_LIMIT", 0)
+	l := &rateLimiter{interval: envDuration(envName("HCU_PLUGIN_RATE_LIMIT_", name), def)}
+	limiters[name] = l
+	return l
+}
+
+// guardedProvider wraps a provider with rate limit and timeout so a slow
+// or hung plugin could not block its callers forever.
+type guardedProvider struct {
+	spec.Provider
+	name    string
+	limiter *rateLimiter
+	timeout time.Duration
+}
+
+// guard returns the provider wrapped with the configuration of the plugin.
+func guard(name string, p spec.Provider) spec.Provider {
+	return &guardedProvider{
+		Provider: p,
+		name:     name,
+		limiter:  limiterFor(name),
+		timeout:  envDuration("HCU_PLUGIN_TIMEOUT", DefaultCallTimeout),
+	}
+}
+
+type callResult struct {
+	list []interface{}
+	uid  int
+	gid  int
+	err  error
+}
+
+// aborter is implemented by plugins which could abort running call.
+type aborter interface {
+	abort()
+}
+
+// call runs the function with the rate limit and timeout. After timeout,
+// the connection of out-of-process plugin is aborted so it does not keep
+// the plugin busy. In-process function keeps running in background but
+// its result is dropped.
+func (g *guardedProvider) call(method string, fn func() callResult) callResult {
+	g.limiter.Wait()
+	if g.timeout <= 0 {
+		return fn()
+	}
+	ch := make(chan callResult, 1)
+	go func() {
+		ch <- fn()
+	}()
+	select {
+	case res := <-ch:
+		return res
+	case <-time.After(g.timeout):
+		plogger.Errorf("plugin %v: %v did not return in %v", g.name, method, g.timeout)
+		if a, ok := g.Provider.(aborter); ok {
+			a.abort()
+		}
+		return callResult{err: ErrTimeout}
+	}
+}
+
+// CheckAccount implements spec.Provider
+func (g *guardedProvider) CheckAccount(user, pass string) (int, int, error) {
+	res := g.call("CheckAccount", func() callResult {
+		uid, gid, err := g.Provider.CheckAccount(user, pass)
+		return callResult{uid: uid, gid: gid, err: err}
+	})
+	return res.uid, res.gid, res.err
+}
+
+// GetResources implements spec.Provider
+func (g *guardedProvider) GetResources(user, pass string) ([]interface{}, error) {
+	res := g.call("GetResources", func() callResult {
+		list, err := g.Provider.GetResources(user, pass)
+		return callResult{list: list, err: err}
+	})
+	return res.list, res.err
+}
+
+// GetNotifications implements spec.Provider
+func (g *guardedProvider) GetNotifications(user, pass string, since time.Time) ([]interface{}, error) {
+	res := g.call("GetNotifications", func() callResult {
+		list, err := g.Provider.GetNotifications(user, pass, since)
+		return callResult{list: list, err: err}
+	})
+	return res.list, res.err
+}
+
+// envDuration returns duration configured by the environment variable or
+// given default value if it is not set or invalid.
+func envDuration(key string, def time.Duration) time.Duration {
+	d, err := utils.DurationFromEnv(key, def)
+	if err != nil {
+		plogger.Warnf("%v. use default %v", err, def)
+	}
+	return d
+}
+
+// envName returns environment variable name for the plugin name.
+func envName(prefix, name string) string {
+	return prefix + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,90 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type slowProvider struct {
+	testProvider
+	delay time.Duration
+}
+
+func (p slowProvider) GetResources(user, pass string) ([]interface{}, error) {
+	time.Sleep(p.delay)
+	return p.testProvider.GetResources(user, pass)
+}
+
+func Test_GuardedProvider_Timeout(t *testing.T) {
+	r := require.New(t)
+
+	g := &guardedProvider{
+		Provider: slowProvider{delay: 200 * time.Millisecond},
+		name:     "slow",
+		timeout:  20 * time.Millisecond,
+	}
+	_, err := g.GetResources("user", "secret")
+	r.Equal(ErrTimeout, err)
+
+	uid, gid, err := g.CheckAccount("user", "secret")
+	r.NoError(err)
+	r.Equal(100, uid)
+	r.Equal(200, gid)
+
+	g.timeout = time.Second
+	resources, err := g.GetResources("user", "secret")
+	r.NoError(err)
+	r.Len(resources, 2)
+}
+
+func Test_GuardedProvider_Abort(t *testing.T) {
+	r := require.New(t)
+	dir, err := ioutil.TempDir("", "plugins")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	provider := serveTestProvider(r, dir, slowProvider{delay: 5 * time.Second})
+	defer provider.Close()
+
+	g := &guardedProvider{
+		Provider: provider,
+		name:     "slow",
+		timeout:  50 * time.Millisecond,
+	}
+	_, err = g.GetResources("user", "secret")
+	r.Equal(ErrTimeout, err)
+
+	// the hung call was aborted and the next call connects again
+	start := time.Now()
+	uid, _, err := g.CheckAccount("user", "secret")
+	r.NoError(err)
+	r.Equal(100, uid)
+	r.True(time.Since(start) < time.Second)
+}
+
+func Test_RateLimiter(t *testing.T) {
+	r := require.New(t)
+
+	l := &rateLimiter{interval: 30 * time.Millisecond}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		l.Wait()
+	}
+	r.True(time.Since(start) >= 60*time.Millisecond)
+
+	var nolimit *rateLimiter
+	start = time.Now()
+	nolimit.Wait()
+	r.True(time.Since(start) < 10*time.Millisecond)
+}
+
+func Test_EnvName(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal("HCU_PLUGIN_RATE_LIMIT_SOFTLAYER", envName("HCU_PLUGIN_RATE_LIMIT_", "softlayer"))
+	r.Equal("HCU_PLUGIN_RATE_LIMIT_MY_CLOUD2", envName("HCU_PLUGIN_RATE_LIMIT_", "my-cloud2"))
+}
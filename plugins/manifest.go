@@ -0,0 +1,108 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SpecVersion is the version of plugin interface supported by this host.
+// Plugins declare the version they are built for with their manifest and
+// plugins with different version are refused.
+const SpecVersion = 1
+
+// capabilities of plugins
+const (
+	CapResources     = "resources"
+	CapNotifications = "notifications"
+	CapResource      = "resource"
+	CapPower         = "power"
+)
+
+// legacyCapabilities is assumed capabilities for plugins without manifest.
+var legacyCapabilities = map[string][]string{
+	ClassProvider: {CapResources, CapNotifications},
+	ClassNotifier: Events,
+}
+
+// Manifest is a declaration of plugin's identity and capabilities.
+type Manifest struct {
+	Name         string   `json:"name"`
+	Version      string   `json:"version"`
+	SpecVersion  int      `json:"spec_version"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// Manifester is an optional interface for plugins to declare its manifest.
+// It uses builtin types only so go plugins do not need to import this
+// package and its dependencies.
+type Manifester interface {
+	Manifest() (name, version string, specVersion int, capabilities []string)
+}
+
+// String returns name and version of the manifest.
+func (m Manifest) String() string {
+	return m.Name + " " + m.Version
+}
+
+// IsLegacy returns true if the plugin does not provide manifest.
+func (m Manifest) IsLegacy() bool {
+	return m.SpecVersion == 0
+}
+
+// Has returns true if the plugin has all of given capabilities.
+func (m Manifest) Has(caps ...string) bool {
+	for _, c := range caps {
+		found := false
+		for _, e := range m.Capabilities {
+			if e == c {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// CapabilityList returns comma separated capabilities.
+func (m Manifest) CapabilityList() string {
+	return strings.Join(m.Capabilities, ", ")
+}
+
+//*** local functions
+
+// getManifest returns manifest of the plugin symbol. if the symbol does
+// not implement Manifester, legacy manifest is returned.
+func getManifest(class, name string, symbol interface{}) Manifest {
+	if m, ok := symbol.(Manifester); ok {
+		n, v, s, caps := m.Manifest()
+		if s > 0 {
+			return Manifest{Name: n, Version: v, SpecVersion: s, Capabilities: caps}
+		}
+	}
+	return Manifest{
+		Name:         name,
+		Version:      "unknown",
+		Capabilities: legacyCapabilities[class],
+	}
+}
+
+// checkManifest returns error if the plugin is not compatible with host.
+func checkManifest(class string, m Manifest) error {
+	if !m.IsLegacy() && m.SpecVersion != SpecVersion {
+		return fmt.Errorf("incompatible spec version %v (host supports %v)", m.SpecVersion, SpecVersion)
+	}
+	switch class {
+	case ClassProvider:
+		if !m.Has(CapResources) && !m.Has(CapNotifications) {
+			return fmt.Errorf("provider plugin without %v or %v capability", CapResources, CapNotifications)
+		}
+	case ClassNotifier:
+		if len(m.Capabilities) == 0 {
+			return fmt.Errorf("notifier plugin without any event to subscribe")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type manifestedProvider struct {
+	testProvider
+	specVersion int
+}
+
+func (p manifestedProvider) Manifest() (string, string, int, []string) {
+	return "test", "1.2.3", p.specVersion, []string{CapResources, CapPower}
+}
+
+func Test_GetManifest(t *testing.T) {
+	r := require.New(t)
+
+	m := getManifest(ClassProvider, "legacy", testProvider{})
+	r.True(m.IsLegacy())
+	r.Equal("legacy", m.Name)
+	r.True(m.Has(CapResources, CapNotifications))
+	r.False(m.Has(CapPower))
+
+	m = getManifest(ClassProvider, "test", manifestedProvider{specVersion: SpecVersion})
+	r.False(m.IsLegacy())
+	r.Equal("test 1.2.3", m.String())
+	r.True(m.Has(CapResources, CapPower))
+	r.False(m.Has(CapNotifications))
+	r.Equal("resources, power", m.CapabilityList())
+}
+
+func Test_CheckManifest(t *testing.T) {
+	r := require.New(t)
+
+	r.NoError(checkManifest(ClassProvider, getManifest(ClassProvider, "legacy", testProvider{})))
+	r.NoError(checkManifest(ClassProvider, getManifest(ClassProvider, "test", manifestedProvider{specVersion: SpecVersion})))
+	r.Error(checkManifest(ClassProvider, getManifest(ClassProvider, "test", manifestedProvider{specVersion: SpecVersion + 1})))
+	r.Error(checkManifest(ClassProvider, Manifest{Name: "x", SpecVersion: SpecVersion, Capabilities: []string{CapPower}}))
+}
@@ -0,0 +1,69 @@
+package plugins
+
+import (
+	"errors"
+)
+
+// events delivered to notifiers
+const (
+	EventIncidentOpened = "incident.opened"
+	EventIncidentClosed = "incident.closed"
+	EventSyncFailed     = "sync.failed"
+)
+
+// Events is a list of all events. Notifiers without manifest receive all
+// of them and notifiers with manifest receive events declared as their
+// capabilities.
+var Events = []string{EventIncidentOpened, EventIncidentClosed, EventSyncFailed}
+
+// Notifier is an interface for notifier plugins which deliver messages to
+// chat, mail, webhooks, and so on.
+// It uses builtin types only as Manifester does.
+type Notifier interface {
+	Notify(event, subject, body string, fields map[string]string) error
+}
+
+// GetNotifier returns notifier via plugin
+func GetNotifier(name string) (Notifier, error) {
+	p, err := registry.Get(name, ClassNotifier)
+	if err != nil {
+		return nil, err
+	}
+	return p.Notifier()
+}
+
+// Notify delivers the message to all notifiers subscribing the event.
+// Failures on each notifier are logged and the number of successful
+// delivery is returned.
+func Notify(event, subject, body string, fields map[string]string) int {
+	sent := 0
+	for _, p := range registry.List(ClassNotifier) {
+		if !p.IsLoaded() || !p.Has(event) {
+			continue
+		}
+		notifier, err := p.Notifier()
+		if err != nil {
+			plogger.Errorf("could not use notifier %v: %v", p, err)
+			continue
+		}
+		if err := notifier.Notify(event, subject, body, fields); err != nil {
+			plogger.Errorf("could not deliver %v via %v: %v", event, p, err)
+			continue
+		}
+		sent++
+	}
+	plogger.Debugf("%v message '%v' was delivered to %v notifiers", event, subject, sent)
+	return sent
+}
+
+// Notifier returns notifier instance of the plugin.
+func (p *Plugin) Notifier() (Notifier, error) {
+	if !p.IsLoaded() {
+		return nil, errors.New("plugin not loaded: " + p.Error)
+	}
+	notifier, ok := p.Symbol.(Notifier)
+	if !ok {
+		return nil, errors.New("invalid plugin")
+	}
+	return notifier, nil
+}
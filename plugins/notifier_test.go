@@ -0,0 +1,79 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testNotifier struct {
+	events []string
+	fail   bool
+}
+
+func (n *testNotifier) Notify(event, subject, body string, fields map[string]string) error {
+	if n.fail {
+		return errors.New("delivery failed")
+	}
+	n.events = append(n.events, event)
+	return nil
+}
+
+type closedOnlyNotifier struct {
+	testNotifier
+}
+
+func (n *closedOnlyNotifier) Manifest() (string, string, int, []string) {
+	return "closed", "0.1.0", SpecVersion, []string{EventIncidentClosed}
+}
+
+func Test_Notify(t *testing.T) {
+	r := require.New(t)
+	saved := registry
+	defer func() { registry = saved }()
+	registry = NewRegistry("/nonexistent")
+
+	all := &testNotifier{}
+	closed := &closedOnlyNotifier{}
+	broken := &testNotifier{fail: true}
+	r.NoError(Register(ClassNotifier, "all", all))
+	r.NoError(Register(ClassNotifier, "closed", closed))
+	r.NoError(Register(ClassNotifier, "broken", broken))
+	r.Error(Register(ClassNotifier, "invalid", testProvider{}))
+	r.Len(List(ClassNotifier), 3)
+
+	r.Equal(1, Notify(EventIncidentOpened, "opened", "body", nil))
+	r.Equal(2, Notify(EventIncidentClosed, "closed", "body", nil))
+	r.Equal([]string{EventIncidentOpened, EventIncidentClosed}, all.events)
+	r.Equal([]string{EventIncidentClosed}, closed.events)
+
+	n, err := GetNotifier("closed")
+	r.NoError(err)
+	r.Equal(closed, n)
+}
+
+func Test_WebhookNotifier(t *testing.T) {
+	r := require.New(t)
+
+	var payload webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL)
+	err := notifier.Notify(EventSyncFailed, "sync failed", "timeout", map[string]string{"kind": "resource"})
+	r.NoError(err)
+	r.Equal(EventSyncFailed, payload.Event)
+	r.Equal("sync failed\ntimeout", payload.Text)
+	r.Equal("resource", payload.Fields["kind"])
+
+	notifier.URL = server.URL + "/%zz"
+	r.Error(notifier.Notify(EventSyncFailed, "sync failed", "timeout", nil))
+}
@@ -1,70 +1,92 @@
 package plugins
 
 import (
-	"errors"
-	"io/ioutil"
-	"os"
-	"plugin"
-	"strings"
+	"fmt"
+	"time"
 
 	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/logger"
 	"github.com/hyeoncheon/spec"
+
+	"github.com/hyeoncheon/honcheonui/plugins/fake"
+)
+
+// constants
+const (
+	DefaultWatchPeriod = 1 * time.Minute
 )
 
-// TODO: plugin caching
-// TODO: reload if updated
-// TODO: plugin types
+var registry = NewRegistry(pluginHome())
+var plogger = logger.NewLogger("Debug").WithField("category", "plugin")
 
-// GetPlugin returns provider via plugin
-func GetPlugin(name, class string) (spec.Provider, error) {
-	// TODO: am I need to seperate provider plugin and messaging plugins?
-	name = class + "-" + name + ".so"
-	plug, err := plugin.Open(os.Getenv("HCU_HOME") + "/plugins/" + name)
+// InitPlugins loads all plugins on the plugin home and starts watching it.
+func InitPlugins(app *buffalo.App) error {
+	plogger = app.Logger.WithField("category", "plugin")
+	plogger.Infof("loading plugins from %v...", pluginHome())
+
+	period, err := time.ParseDuration(envy.Get("HCU_PLUGIN_WATCH_PERIOD", DefaultWatchPeriod.String()))
 	if err != nil {
-		return nil, err
+		plogger.Warnf("invalid plugin watch period. use default: %v", err)
+		period = DefaultWatchPeriod
+	}
+	if url := envy.Get("HCU_WEBHOOK_URL", ""); url != "" {
+		if err := Register(ClassNotifier, "webhook", NewWebhookNotifier(url)); err != nil {
+			plogger.Errorf("could not register webhook notifier: %v", err)
+		}
+	}
+	if envy.Get("HCU_FAKE_PROVIDER", "") == "true" {
+		if err := Register(ClassProvider, fake.Name, fake.New(envy.Get("HCU_FAKE_FIXTURE", ""))); err != nil {
+			plogger.Errorf("could not register fake provider: %v", err)
+		}
 	}
+	err = registry.Scan()
+	registry.Watch(period)
+	return err
+}
 
-	symbol, err := plug.Lookup("Provider")
+// GetPlugin returns provider via plugin. If capabilities are given,
+// it returns error when the plugin does not have one of them. Calls to the
+// provider are rate limited and timed out as configured.
+func GetPlugin(name, class string, caps ...string) (spec.Provider, error) {
+	p, err := registry.Get(name, class)
 	if err != nil {
 		return nil, err
 	}
-
-	var provider spec.Provider
-	provider, ok := symbol.(spec.Provider)
-	if !ok {
-		return nil, errors.New("invalid plugin")
+	provider, err := p.Provider()
+	if err != nil {
+		return nil, err
 	}
-	// TODO: version check and logging
-	return provider, nil
-}
-
-func checkPlugin(name, class string) error {
-	if _, err := GetPlugin(name, class); err != nil {
-		return err
+	if !p.Has(caps...) {
+		return nil, fmt.Errorf("plugin %v does not support %v", p, caps)
 	}
-	return nil
+	plogger.Debugf("using plugin %v: %v", p, p.Manifest)
+	return guard(p.Name, provider), nil
 }
 
-// GetPluginList returns an array of plugin names
-func GetPluginList(c buffalo.Context, class string) []string {
+// GetPluginList returns an array of names of loaded plugins
+func GetPluginList(class string) []string {
 	var plugins []string
-
-	pluginHome := os.Getenv("HCU_HOME") + "/plugins"
-	c.Logger().WithField("category", "plugin").Debugf("searching on %v", pluginHome)
-	files, err := ioutil.ReadDir(pluginHome)
-	if err != nil {
-		// error
-		return plugins
-	}
-	for _, f := range files {
-		name := f.Name()
-		if strings.HasPrefix(name, class+"-") && strings.HasSuffix(name, ".so") {
-			name = strings.TrimSuffix(strings.TrimPrefix(name, class+"-"), ".so")
-			if err := checkPlugin(name, class); err == nil {
-				plugins = append(plugins, name)
-			}
+	for _, p := range registry.List(class) {
+		if p.IsLoaded() {
+			plugins = append(plugins, p.Name)
 		}
 	}
-	c.Logger().WithField("category", "plugin").Debugf("plugins found: %v", plugins)
+	plogger.Debugf("plugins found: %v", plugins)
 	return plugins
 }
+
+// List returns all plugins of the class including failed ones.
+func List(class string) []*Plugin {
+	return registry.List(class)
+}
+
+// Register adds in-process plugin.
+func Register(class, name string, symbol interface{}) error {
+	return registry.Register(class, name, symbol)
+}
+
+// Reload reloads all plugins on the plugin home.
+func Reload() error {
+	return registry.Reload()
+}
@@ -0,0 +1,414 @@
+package plugins
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/hyeoncheon/spec"
+)
+
+// plugin classes
+const (
+	ClassProvider = "provider"
+	ClassNotifier = "notifier"
+)
+
+// plugin transports
+const (
+	TransportGo      = "go"
+	TransportExec    = "exec"
+	TransportSocket  = "socket"
+	TransportBuiltin = "builtin"
+)
+
+// Plugin is a loaded plugin with information of its source file.
+type Plugin struct {
+	Name      string      `json:"name"`
+	Class     string      `json:"class"`
+	Transport string      `json:"transport"`
+	Path      string      `json:"path"`
+	ModTime   time.Time   `json:"mod_time"`
+	LoadedAt  time.Time   `json:"loaded_at"`
+	Manifest  Manifest    `json:"manifest"`
+	Symbol    interface{} `json:"-"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// String returns class and name of the plugin.
+func (p Plugin) String() string {
+	return p.Class + "-" + p.Name
+}
+
+// IsLoaded returns true if the plugin was loaded without error.
+func (p Plugin) IsLoaded() bool {
+	return p.Symbol != nil && p.Error == ""
+}
+
+// Has returns true if the plugin has all of given capabilities.
+func (p Plugin) Has(caps ...string) bool {
+	return p.Manifest.Has(caps...)
+}
+
+// close closes connection to out-of-process plugin.
+func (p *Plugin) close() {
+	if c, ok := p.Symbol.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			plogger.Warnf("could not close plugin %v: %v", p, err)
+		}
+	}
+}
+
+// check validates the symbol against the interface of the plugin class
+// and sets the manifest of the plugin.
+func (p *Plugin) check() error {
+	var err error
+	switch p.Class {
+	case ClassProvider:
+		_, err = p.Provider()
+	case ClassNotifier:
+		_, err = p.Notifier()
+	default:
+		err = errors.New("unknown plugin class: " + p.Class)
+	}
+	if err != nil {
+		return err
+	}
+	p.Manifest = getManifest(p.Class, p.Name, p.Symbol)
+	return checkManifest(p.Class, p.Manifest)
+}
+
+// Provider returns provider instance of the plugin.
+func (p *Plugin) Provider() (spec.Provider, error) {
+	if !p.IsLoaded() {
+		return nil, errors.New("plugin not loaded: " + p.Error)
+	}
+	provider, ok := p.Symbol.(spec.Provider)
+	if !ok {
+		return nil, errors.New("invalid plugin")
+	}
+	return provider, nil
+}
+
+// Registry is a cache of loaded plugins on the plugin home directory.
+// Each plugin file is opened once and the instance is reused until
+// the file is updated or removed.
+type Registry struct {
+	mu      sync.RWMutex
+	home    string
+	plugins map[string]*Plugin
+	stop    chan struct{}
+}
+
+// NewRegistry returns new registry for the given plugin home.
+func NewRegistry(home string) *Registry {
+	return &Registry{
+		home:    home,
+		plugins: map[string]*Plugin{},
+	}
+}
+
+// Get returns cached plugin. if the plugin is not cached yet, the registry
+// rescans plugin home once and try again.
+func (r *Registry) Get(name, class string) (*Plugin, error) {
+	key := class + "-" + name
+	r.mu.RLock()
+	p := r.plugins[key]
+	r.mu.RUnlock()
+	if p == nil {
+		if err := r.Scan(); err != nil {
+			return nil, err
+		}
+		r.mu.RLock()
+		p = r.plugins[key]
+		r.mu.RUnlock()
+	}
+	if p == nil {
+		return nil, errors.New("could not find plugin " + key)
+	}
+	return p, nil
+}
+
+// List returns plugins of the class sorted by name.
+func (r *Registry) List(class string) []*Plugin {
+	list := []*Plugin{}
+	r.mu.RLock()
+	for _, p := range r.plugins {
+		if p.Class == class {
+			list = append(list, p)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Register adds in-process plugin to the registry. Builtin plugins are
+// kept until the process ends.
+func (r *Registry) Register(class, name string, symbol interface{}) error {
+	p := &Plugin{
+		Name:      name,
+		Class:     class,
+		Transport: TransportBuiltin,
+		LoadedAt:  time.Now(),
+		Symbol:    symbol,
+	}
+	if err := p.check(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.plugins[p.String()] = p
+	r.mu.Unlock()
+	plogger.Infof("builtin plugin %v registered: %v %v", p, p.Manifest, p.Manifest.Capabilities)
+	return nil
+}
+
+// Reload drops all cached plugins and scans plugin home again.
+// Out-of-process plugins are restarted but go plugins could not be
+// unloaded. Reloading them just refreshes their state on the registry.
+func (r *Registry) Reload() error {
+	dropped := []*Plugin{}
+	r.mu.Lock()
+	for key, p := range r.plugins {
+		if p.Transport != TransportBuiltin {
+			dropped = append(dropped, p)
+			delete(r.plugins, key)
+		}
+	}
+	r.mu.Unlock()
+	closeAll(dropped)
+	return r.Scan()
+}
+
+// Scan searches plugin home and loads new or updated plugins.
+// Plugins which no longer exist on the plugin home are removed.
+//
+// Opening plugins could take a while (process start, connection) so they
+// are loaded without holding the lock and swapped in afterward.
+func (r *Registry) Scan() error {
+	files, err := ioutil.ReadDir(r.home)
+	if err != nil {
+		plogger.Errorf("could not read plugin home %v: %v", r.home, err)
+		return err
+	}
+
+	type candidate struct {
+		key    string
+		cached *Plugin
+		loaded *Plugin
+		file   os.FileInfo
+	}
+	found := map[string]bool{}
+	candidates := []*candidate{}
+	r.mu.RLock()
+	for _, f := range files {
+		class, name, transport, ok := parseFileName(f.Name())
+		if !ok || !isPluginFile(f, transport) {
+			continue
+		}
+		key := class + "-" + name
+		found[key] = true
+
+		cached := r.plugins[key]
+		if cached != nil && cached.ModTime.Equal(f.ModTime()) {
+			continue
+		}
+		candidates = append(candidates, &candidate{key: key, cached: cached, file: f})
+	}
+	r.mu.RUnlock()
+
+	for _, c := range candidates {
+		if c.cached != nil && c.cached.IsLoaded() && c.cached.Transport == TransportGo {
+			// go runtime returns already opened plugin for the same path.
+			plogger.Warnf("%v was updated. restart is required to apply it", c.key)
+			continue
+		}
+		class, name, transport, _ := parseFileName(c.file.Name())
+		c.loaded = load(class, name, transport, filepath.Join(r.home, c.file.Name()), c.file.ModTime())
+	}
+
+	dropped := []*Plugin{}
+	r.mu.Lock()
+	for _, c := range candidates {
+		if r.plugins[c.key] != c.cached {
+			// another scan already replaced it while loading.
+			if c.loaded != nil {
+				dropped = append(dropped, c.loaded)
+			}
+			continue
+		}
+		if c.loaded == nil {
+			updated := *c.cached
+			updated.ModTime = c.file.ModTime()
+			r.plugins[c.key] = &updated
+			continue
+		}
+		if c.cached != nil {
+			dropped = append(dropped, c.cached)
+		}
+		r.plugins[c.key] = c.loaded
+	}
+	for key, p := range r.plugins {
+		if !found[key] && p.Transport != TransportBuiltin {
+			plogger.Infof("plugin %v was removed from plugin home", key)
+			dropped = append(dropped, p)
+			delete(r.plugins, key)
+		}
+	}
+	r.mu.Unlock()
+	closeAll(dropped)
+	return nil
+}
+
+// Watch scans plugin home periodically until Stop is called.
+func (r *Registry) Watch(period time.Duration) {
+	r.mu.Lock()
+	if r.stop != nil {
+		r.mu.Unlock()
+		return
+	}
+	r.stop = make(chan struct{})
+	stop := r.stop
+	r.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.Scan()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops watching of plugin home.
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+//*** local functions
+
+// parseFileName returns class, name, and transport of the plugin from
+// file name formatted as class-name.so (go plugin), class-name.sock (rpc
+// over unix domain socket), or class-name (rpc over stdio of executable).
+func parseFileName(fileName string) (string, string, string, bool) {
+	var transport string
+	ext := filepath.Ext(fileName)
+	switch ext {
+	case ".so":
+		transport = TransportGo
+	case ".sock":
+		transport = TransportSocket
+	case "":
+		transport = TransportExec
+	default:
+		return "", "", "", false
+	}
+	parts := strings.SplitN(strings.TrimSuffix(fileName, ext), "-", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], transport, true
+}
+
+// closeAll closes plugins which were dropped from the registry.
+func closeAll(plugins []*Plugin) {
+	for _, p := range plugins {
+		p.close()
+	}
+}
+
+// isPluginFile returns true if the file type is matched with transport.
+func isPluginFile(f os.FileInfo, transport string) bool {
+	switch transport {
+	case TransportSocket:
+		return f.Mode()&os.ModeSocket != 0
+	case TransportExec:
+		return f.Mode().IsRegular() && f.Mode().Perm()&0111 != 0
+	default:
+		return f.Mode().IsRegular()
+	}
+}
+
+func load(class, name, transport, path string, modTime time.Time) *Plugin {
+	p := &Plugin{
+		Name:      name,
+		Class:     class,
+		Transport: transport,
+		Path:      path,
+		ModTime:   modTime,
+		LoadedAt:  time.Now(),
+	}
+	plogger.Debugf("loading %v plugin %v from %v", transport, p, path)
+
+	symbol, err := open(class, transport, path)
+	if err != nil {
+		plogger.Errorf("could not load plugin %v: %v", p, err)
+		p.Error = err.Error()
+		return p
+	}
+	p.Symbol = symbol
+	if err := p.check(); err != nil {
+		plogger.Errorf("refused plugin %v: %v", p, err)
+		p.Error = err.Error()
+		p.close()
+		p.Symbol = nil
+		return p
+	}
+	if p.Manifest.IsLegacy() {
+		plogger.Warnf("plugin %v has no manifest. assume legacy capabilities", p)
+	} else if p.Manifest.Name != name {
+		plogger.Warnf("plugin %v declares different name %v", p, p.Manifest.Name)
+	}
+	plogger.Infof("plugin %v loaded: %v (spec %v) %v", p, p.Manifest, p.Manifest.SpecVersion, p.Manifest.Capabilities)
+	return p
+}
+
+// open opens the plugin file with given transport and returns its symbol.
+func open(class, transport, path string) (interface{}, error) {
+	if transport == TransportGo {
+		return openGoPlugin(class, path)
+	}
+	switch class {
+	case ClassProvider:
+		return newRPCProvider(path, transport)
+	case ClassNotifier:
+		return newRPCNotifier(path, transport)
+	}
+	return nil, errors.New("unknown plugin class: " + class)
+}
+
+// openGoPlugin opens go plugin and returns the symbol of plugin class.
+func openGoPlugin(class, path string) (interface{}, error) {
+	plug, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return plug.Lookup(symbolName(class))
+}
+
+// symbolName returns exported symbol name for the plugin class.
+func symbolName(class string) string {
+	return strings.Title(class)
+}
+
+func pluginHome() string {
+	return envy.Get("HCU_HOME", "") + "/plugins"
+}
@@ -0,0 +1,115 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseFileName(t *testing.T) {
+	r := require.New(t)
+
+	class, name, transport, ok := parseFileName("provider-softlayer.so")
+	r.True(ok)
+	r.Equal("provider", class)
+	r.Equal("softlayer", name)
+	r.Equal(TransportGo, transport)
+
+	class, name, transport, ok = parseFileName("provider-aws-ec2")
+	r.True(ok)
+	r.Equal("provider", class)
+	r.Equal("aws-ec2", name)
+	r.Equal(TransportExec, transport)
+
+	_, name, transport, ok = parseFileName("provider-remote.sock")
+	r.True(ok)
+	r.Equal("remote", name)
+	r.Equal(TransportSocket, transport)
+
+	for _, fileName := range []string{"provider.so", "provider-.so", "-x.so", "provider-x.json", "README"} {
+		_, _, _, ok = parseFileName(fileName)
+		r.False(ok, fileName)
+	}
+}
+
+func Test_Registry_Scan(t *testing.T) {
+	r := require.New(t)
+	home, err := ioutil.TempDir("", "plugins")
+	r.NoError(err)
+	defer os.RemoveAll(home)
+
+	r.NoError(ioutil.WriteFile(filepath.Join(home, "provider-broken.so"), []byte("not a plugin"), 0644))
+	r.NoError(ioutil.WriteFile(filepath.Join(home, "README"), []byte("readme"), 0644))
+
+	registry := NewRegistry(home)
+	r.NoError(registry.Scan())
+
+	list := registry.List("provider")
+	r.Len(list, 1)
+	r.Equal("broken", list[0].Name)
+	r.False(list[0].IsLoaded())
+	r.NotEmpty(list[0].Error)
+
+	p, err := registry.Get("broken", "provider")
+	r.NoError(err)
+	_, err = p.Provider()
+	r.Error(err)
+
+	r.NoError(os.Remove(filepath.Join(home, "provider-broken.so")))
+	r.NoError(registry.Scan())
+	r.Len(registry.List("provider"), 0)
+
+	_, err = registry.Get("broken", "provider")
+	r.Error(err)
+}
+
+func Test_Registry_ConcurrentScan(t *testing.T) {
+	r := require.New(t)
+	home, err := ioutil.TempDir("", "plugins")
+	r.NoError(err)
+	defer os.RemoveAll(home)
+
+	path := filepath.Join(home, "provider-broken.so")
+	r.NoError(ioutil.WriteFile(path, []byte("not a plugin"), 0644))
+
+	registry := NewRegistry(home)
+	r.NoError(registry.Scan())
+	r.NoError(os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	done := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() { done <- registry.Scan() }()
+	}
+	for i := 0; i < 4; i++ {
+		registry.List(ClassProvider)
+		r.NoError(<-done)
+	}
+
+	list := registry.List(ClassProvider)
+	r.Len(list, 1)
+	info, err := os.Stat(path)
+	r.NoError(err)
+	r.True(list[0].ModTime.Equal(info.ModTime()))
+}
+
+func Test_GetPlugin_NotLoaded(t *testing.T) {
+	r := require.New(t)
+	home, err := ioutil.TempDir("", "plugins")
+	r.NoError(err)
+	defer os.RemoveAll(home)
+
+	r.NoError(ioutil.WriteFile(filepath.Join(home, "provider-broken.so"), []byte("not a plugin"), 0644))
+
+	saved := registry
+	defer func() { registry = saved }()
+	registry = NewRegistry(home)
+
+	// load error should be reported instead of missing capabilities
+	_, err = GetPlugin("broken", ClassProvider, CapResources)
+	r.Error(err)
+	r.Contains(err.Error(), "plugin not loaded")
+}
@@ -0,0 +1,456 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/hyeoncheon/spec"
+)
+
+// Out-of-process plugins are separated executables or servers speaking
+// JSON-RPC 1.0 (net/rpc/jsonrpc) on stdio or unix domain socket. They do
+// not share the toolchain and dependencies with honcheonui binary.
+//
+// A provider plugin implements spec.Provider and calls ServeProvider (for
+// stdio executables) or ListenAndServeProvider (for socket servers) from
+// its main function. Notifier plugins use ServeNotifier and
+// ListenAndServeNotifier in the same way.
+
+// constants for rpc transport
+const (
+	rpcServiceProvider = "Provider"
+	rpcServiceNotifier = "Notifier"
+	rpcDialTimeout     = 5 * time.Second
+)
+
+// CredentialArgs is an argument for rpc calls which need an account.
+type CredentialArgs struct {
+	User string
+	Pass string
+}
+
+// NotificationArgs is an argument for GetNotifications rpc call.
+type NotificationArgs struct {
+	CredentialArgs
+	Since time.Time
+}
+
+// ManifestArgs is an argument for Manifest rpc call.
+type ManifestArgs struct{}
+
+// NotifyArgs is an argument for Notify rpc call.
+type NotifyArgs struct {
+	Event   string
+	Subject string
+	Body    string
+	Fields  map[string]string
+}
+
+// NotifyReply is a reply of Notify rpc call.
+type NotifyReply struct{}
+
+// AccountReply is a reply of CheckAccount rpc call.
+type AccountReply struct {
+	UserID  int
+	GroupID int
+}
+
+// ResourcesReply is a reply of GetResources rpc call.
+type ResourcesReply struct {
+	Resources []spec.HoncheonuiResource
+}
+
+// NotificationsReply is a reply of GetNotifications rpc call.
+type NotificationsReply struct {
+	Notifications []spec.HoncheonuiNotification
+}
+
+//*** server side
+
+// ProviderServer exports spec.Provider as rpc service.
+type ProviderServer struct {
+	Provider spec.Provider
+}
+
+// Manifest returns manifest of the provider if it implements Manifester.
+func (s *ProviderServer) Manifest(args ManifestArgs, reply *Manifest) error {
+	return manifestOf(s.Provider, reply)
+}
+
+// CheckAccount calls CheckAccount of the provider.
+func (s *ProviderServer) CheckAccount(args CredentialArgs, reply *AccountReply) error {
+	uid, gid, err := s.Provider.CheckAccount(args.User, args.Pass)
+	if err != nil {
+		return err
+	}
+	reply.UserID = uid
+	reply.GroupID = gid
+	return nil
+}
+
+// GetResources calls GetResources of the provider.
+func (s *ProviderServer) GetResources(args CredentialArgs, reply *ResourcesReply) error {
+	resources, err := s.Provider.GetResources(args.User, args.Pass)
+	if err != nil {
+		return err
+	}
+	return convert(resources, &reply.Resources)
+}
+
+// GetNotifications calls GetNotifications of the provider.
+func (s *ProviderServer) GetNotifications(args NotificationArgs, reply *NotificationsReply) error {
+	notes, err := s.Provider.GetNotifications(args.User, args.Pass, args.Since)
+	if err != nil {
+		return err
+	}
+	// malformed notifications could not be sent over the wire. skip them
+	// instead of failing whole notifications.
+	reply.Notifications = []spec.HoncheonuiNotification{}
+	for _, n := range notes {
+		note := spec.HoncheonuiNotification{}
+		if err := convert(n, &note); err != nil {
+			log.Printf("skip malformed notification %v: %v", n, err)
+			continue
+		}
+		reply.Notifications = append(reply.Notifications, note)
+	}
+	return nil
+}
+
+// NotifierServer exports Notifier as rpc service.
+type NotifierServer struct {
+	Notifier Notifier
+}
+
+// Manifest returns manifest of the notifier if it implements Manifester.
+func (s *NotifierServer) Manifest(args ManifestArgs, reply *Manifest) error {
+	return manifestOf(s.Notifier, reply)
+}
+
+// Notify calls Notify of the notifier.
+func (s *NotifierServer) Notify(args NotifyArgs, reply *NotifyReply) error {
+	return s.Notifier.Notify(args.Event, args.Subject, args.Body, args.Fields)
+}
+
+// ServeProvider serves the provider on stdin and stdout.
+// It blocks until the host closes stdin.
+func ServeProvider(p spec.Provider) error {
+	return serveStdio(rpcServiceProvider, &ProviderServer{Provider: p})
+}
+
+// ListenAndServeProvider serves the provider on the unix domain socket.
+func ListenAndServeProvider(socket string, p spec.Provider) error {
+	return listenAndServe(socket, rpcServiceProvider, &ProviderServer{Provider: p})
+}
+
+// ServeNotifier serves the notifier on stdin and stdout.
+// It blocks until the host closes stdin.
+func ServeNotifier(n Notifier) error {
+	return serveStdio(rpcServiceNotifier, &NotifierServer{Notifier: n})
+}
+
+// ListenAndServeNotifier serves the notifier on the unix domain socket.
+func ListenAndServeNotifier(socket string, n Notifier) error {
+	return listenAndServe(socket, rpcServiceNotifier, &NotifierServer{Notifier: n})
+}
+
+func manifestOf(symbol interface{}, reply *Manifest) error {
+	m, ok := symbol.(Manifester)
+	if !ok {
+		return errors.New("manifest not provided")
+	}
+	reply.Name, reply.Version, reply.SpecVersion, reply.Capabilities = m.Manifest()
+	return nil
+}
+
+func serveStdio(service string, rcvr interface{}) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName(service, rcvr); err != nil {
+		return err
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(&stdio{
+		ReadCloser:  os.Stdin,
+		WriteCloser: os.Stdout,
+	}))
+	return nil
+}
+
+func listenAndServe(socket, service string, rcvr interface{}) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName(service, rcvr); err != nil {
+		return err
+	}
+	os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+//*** client side
+
+// rpcClient is a connection to out-of-process plugin. It connects (or
+// starts the plugin process) again if the connection was broken.
+//
+// mu serializes calls. connMu guards the connection and the process so
+// a timed out call could be aborted while it still holds mu.
+type rpcClient struct {
+	mu        sync.Mutex
+	path      string
+	transport string
+	service   string
+	client    *rpc.Client
+	connMu    sync.Mutex
+	conn      io.Closer
+	cmd       *exec.Cmd
+	busy      bool
+	aborted   bool
+}
+
+func newRPCClient(path, transport, service string) (*rpcClient, error) {
+	c := &rpcClient{path: path, transport: transport, service: service}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Manifest implements Manifester. For plugins without manifest, it returns
+// zero spec version and the plugin is treated as legacy plugin.
+func (c *rpcClient) Manifest() (string, string, int, []string) {
+	reply := &Manifest{}
+	if err := c.call("Manifest", ManifestArgs{}, reply); err != nil {
+		plogger.Debugf("could not get manifest of %v: %v", c.path, err)
+		return "", "", 0, nil
+	}
+	return reply.Name, reply.Version, reply.SpecVersion, reply.Capabilities
+}
+
+// Close closes the connection and stops the plugin process if exists.
+func (c *rpcClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.close()
+}
+
+func (c *rpcClient) call(method string, args, reply interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client == nil {
+		if err := c.connect(); err != nil {
+			return err
+		}
+	}
+	c.setBusy(true)
+	err := c.client.Call(c.service+"."+method, args, reply)
+	if c.setBusy(false) {
+		plogger.Warnf("call %v to plugin %v was aborted", method, c.path)
+		c.close()
+		return ErrTimeout
+	}
+	if err == rpc.ErrShutdown || err == io.EOF || err == io.ErrUnexpectedEOF {
+		plogger.Warnf("connection to plugin %v was broken. reconnecting...", c.path)
+		c.close()
+		if err := c.connect(); err != nil {
+			return err
+		}
+		err = c.client.Call(c.service+"."+method, args, reply)
+	}
+	return err
+}
+
+// setBusy marks the client is running a call or not. It returns true if
+// the call was aborted while running.
+func (c *rpcClient) setBusy(busy bool) bool {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	aborted := c.aborted
+	c.busy = busy
+	c.aborted = false
+	return aborted
+}
+
+// abort breaks the connection of the running call so the call returns
+// immediately and the next call connects (or starts the process) again.
+func (c *rpcClient) abort() {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if !c.busy {
+		return
+	}
+	c.aborted = true
+	if c.cmd != nil {
+		c.cmd.Process.Kill()
+	} else if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+func (c *rpcClient) connect() error {
+	switch c.transport {
+	case TransportSocket:
+		conn, err := net.DialTimeout("unix", c.path, rpcDialTimeout)
+		if err != nil {
+			return err
+		}
+		c.connMu.Lock()
+		c.conn = conn
+		c.connMu.Unlock()
+		c.client = jsonrpc.NewClient(conn)
+	case TransportExec:
+		cmd := exec.Command(c.path)
+		cmd.Stderr = os.Stderr
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		plogger.Infof("plugin process %v started (pid %v)", c.path, cmd.Process.Pid)
+		conn := &stdio{ReadCloser: stdout, WriteCloser: stdin}
+		c.connMu.Lock()
+		c.cmd = cmd
+		c.conn = conn
+		c.connMu.Unlock()
+		c.client = jsonrpc.NewClient(conn)
+	default:
+		return errors.New("unsupported transport: " + c.transport)
+	}
+	return nil
+}
+
+func (c *rpcClient) close() error {
+	var err error
+	if c.client != nil {
+		err = c.client.Close()
+		c.client = nil
+	}
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.cmd != nil {
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+		c.cmd = nil
+	}
+	c.conn = nil
+	return err
+}
+
+// rpcProvider implements spec.Provider over rpc connection.
+type rpcProvider struct {
+	*rpcClient
+}
+
+func newRPCProvider(path, transport string) (*rpcProvider, error) {
+	c, err := newRPCClient(path, transport, rpcServiceProvider)
+	if err != nil {
+		return nil, err
+	}
+	return &rpcProvider{c}, nil
+}
+
+// CheckAccount implements spec.Provider
+func (p *rpcProvider) CheckAccount(user, pass string) (int, int, error) {
+	reply := &AccountReply{}
+	err := p.call("CheckAccount", CredentialArgs{User: user, Pass: pass}, reply)
+	return reply.UserID, reply.GroupID, err
+}
+
+// GetResources implements spec.Provider
+func (p *rpcProvider) GetResources(user, pass string) ([]interface{}, error) {
+	reply := &ResourcesReply{}
+	if err := p.call("GetResources", CredentialArgs{User: user, Pass: pass}, reply); err != nil {
+		return nil, err
+	}
+	resources := make([]interface{}, len(reply.Resources))
+	for i, r := range reply.Resources {
+		resources[i] = r
+	}
+	return resources, nil
+}
+
+// GetNotifications implements spec.Provider
+func (p *rpcProvider) GetNotifications(user, pass string, since time.Time) ([]interface{}, error) {
+	reply := &NotificationsReply{}
+	args := NotificationArgs{
+		CredentialArgs: CredentialArgs{User: user, Pass: pass},
+		Since:          since,
+	}
+	if err := p.call("GetNotifications", args, reply); err != nil {
+		return nil, err
+	}
+	notes := make([]interface{}, len(reply.Notifications))
+	for i, n := range reply.Notifications {
+		notes[i] = n
+	}
+	return notes, nil
+}
+
+// rpcNotifier implements Notifier over rpc connection.
+type rpcNotifier struct {
+	*rpcClient
+}
+
+func newRPCNotifier(path, transport string) (*rpcNotifier, error) {
+	c, err := newRPCClient(path, transport, rpcServiceNotifier)
+	if err != nil {
+		return nil, err
+	}
+	return &rpcNotifier{c}, nil
+}
+
+// Notify implements Notifier
+func (n *rpcNotifier) Notify(event, subject, body string, fields map[string]string) error {
+	args := NotifyArgs{Event: event, Subject: subject, Body: body, Fields: fields}
+	return n.call("Notify", args, &NotifyReply{})
+}
+
+//*** helpers
+
+// stdio combines reader and writer pipes as a connection.
+type stdio struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+// Close closes both of reader and writer.
+func (s *stdio) Close() error {
+	werr := s.WriteCloser.Close()
+	if err := s.ReadCloser.Close(); err != nil {
+		return err
+	}
+	return werr
+}
+
+// convert converts data from plugins, usually []interface{}, into typed
+// structure by marshalling and unmarshalling.
+func convert(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
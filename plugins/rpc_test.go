@@ -0,0 +1,135 @@
+package plugins
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyeoncheon/spec"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyeoncheon/honcheonui/plugins/fake"
+)
+
+type testProvider struct{}
+
+func (p testProvider) CheckAccount(user, pass string) (int, int, error) {
+	if pass != "secret" {
+		return 0, 0, errors.New("authentication failed")
+	}
+	return 100, 200, nil
+}
+
+func (p testProvider) GetResources(user, pass string) ([]interface{}, error) {
+	return []interface{}{
+		spec.HoncheonuiResource{Name: "server1", Tags: []string{"web"}},
+		map[string]interface{}{"Name": "server2"},
+	}, nil
+}
+
+func (p testProvider) GetNotifications(user, pass string, since time.Time) ([]interface{}, error) {
+	return []interface{}{
+		spec.HoncheonuiNotification{Title: "maintenance", IssuedAt: since},
+	}, nil
+}
+
+func serveTestProvider(r *require.Assertions, dir string, p spec.Provider) *rpcProvider {
+	socket := filepath.Join(dir, "provider-test.sock")
+	go ListenAndServeProvider(socket, p)
+
+	var provider *rpcProvider
+	var err error
+	for i := 0; i < 50; i++ {
+		if provider, err = newRPCProvider(socket, TransportSocket); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	r.NoError(err)
+	return provider
+}
+
+func Test_RPCProvider_Socket(t *testing.T) {
+	r := require.New(t)
+	dir, err := ioutil.TempDir("", "plugins")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	provider := serveTestProvider(r, dir, testProvider{})
+	defer provider.Close()
+
+	m := getManifest(ClassProvider, "test", provider)
+	r.True(m.IsLegacy())
+
+	uid, gid, err := provider.CheckAccount("user", "secret")
+	r.NoError(err)
+	r.Equal(100, uid)
+	r.Equal(200, gid)
+
+	_, _, err = provider.CheckAccount("user", "wrong")
+	r.EqualError(err, "authentication failed")
+
+	resources, err := provider.GetResources("user", "secret")
+	r.NoError(err)
+	r.Len(resources, 2)
+	r.Equal("server1", resources[0].(spec.HoncheonuiResource).Name)
+	r.Equal([]string{"web"}, resources[0].(spec.HoncheonuiResource).Tags)
+	r.Equal("server2", resources[1].(spec.HoncheonuiResource).Name)
+
+	since := time.Date(2018, 4, 10, 0, 0, 0, 0, time.UTC)
+	notes, err := provider.GetNotifications("user", "secret", since)
+	r.NoError(err)
+	r.Len(notes, 1)
+	r.Equal("maintenance", notes[0].(spec.HoncheonuiNotification).Title)
+	r.True(since.Equal(notes[0].(spec.HoncheonuiNotification).IssuedAt))
+}
+
+func Test_RPCProvider_Manifest(t *testing.T) {
+	r := require.New(t)
+	dir, err := ioutil.TempDir("", "plugins")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	provider := serveTestProvider(r, dir, manifestedProvider{specVersion: SpecVersion})
+	defer provider.Close()
+
+	m := getManifest(ClassProvider, "test", provider)
+	r.False(m.IsLegacy())
+	r.Equal("1.2.3", m.Version)
+	r.Equal(SpecVersion, m.SpecVersion)
+	r.True(m.Has(CapResources, CapPower))
+}
+
+func Test_FakeProvider(t *testing.T) {
+	r := require.New(t)
+	dir, err := ioutil.TempDir("", "plugins")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	registry := NewRegistry(dir)
+	r.NoError(registry.Register(ClassProvider, fake.Name, fake.New("")))
+	p, err := registry.Get(fake.Name, ClassProvider)
+	r.NoError(err)
+	r.True(p.Has(CapResources, CapNotifications))
+
+	inproc, err := p.Provider()
+	r.NoError(err)
+	notes, err := inproc.GetNotifications("fake", "fake", time.Time{})
+	r.NoError(err)
+	r.Len(notes, 4)
+
+	provider := serveTestProvider(r, dir, fake.New(""))
+	defer provider.Close()
+
+	resources, err := provider.GetResources("fake", "fake")
+	r.NoError(err)
+	r.Len(resources, 4)
+
+	// malformed notifications are dropped on the wire
+	notes, err = provider.GetNotifications("fake", "fake", time.Time{})
+	r.NoError(err)
+	r.Len(notes, 3)
+}
@@ -0,0 +1,67 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// constants for webhook notifier
+const (
+	webhookTimeout = 10 * time.Second
+)
+
+// WebhookNotifier is a builtin notifier which posts messages to the URL
+// as JSON. The payload has "text" field also, so it can be used with chat
+// services which support slack compatible incoming webhooks.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// webhookPayload is a structure of webhook message.
+type webhookPayload struct {
+	Text    string            `json:"text"`
+	Event   string            `json:"event"`
+	Subject string            `json:"subject"`
+	Body    string            `json:"body"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// NewWebhookNotifier returns new webhook notifier for the URL.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:    url,
+		Client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Manifest implements Manifester
+func (w *WebhookNotifier) Manifest() (string, string, int, []string) {
+	return "webhook", "builtin", SpecVersion, Events
+}
+
+// Notify implements Notifier
+func (w *WebhookNotifier) Notify(event, subject, body string, fields map[string]string) error {
+	data, err := json.Marshal(&webhookPayload{
+		Text:    subject + "\n" + body,
+		Event:   event,
+		Subject: subject,
+		Body:    body,
+		Fields:  fields,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := w.Client.Post(w.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook responds with status %v", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package secrets
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gobuffalo/envy"
+)
+
+// constants for local secret backends
+const (
+	DefaultSecretsDir = "/run/secrets"
+	EnvPrefix         = "HCU_SECRET_"
+)
+
+// reservedEnv is environment variables with the prefix which should not
+// be handed to plugins such as the application key.
+var reservedEnv = []string{"HCU_SECRET_KEY"}
+
+// resolveFile reads the secret from the file such as docker or kubernetes
+// secrets. The file should be on the secrets directory configured by
+// HCU_SECRETS_DIR. A trailing newline is removed.
+func resolveFile(name string) (string, error) {
+	path, err := secretPath(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveEnv reads the secret from the environment variable. Only the
+// variables prefixed with HCU_SECRET_ are allowed.
+func resolveEnv(name string) (string, error) {
+	if !strings.HasPrefix(name, EnvPrefix) || len(name) == len(EnvPrefix) {
+		return "", errors.New("environment variable is not allowed: " + name)
+	}
+	for _, reserved := range reservedEnv {
+		if name == reserved {
+			return "", errors.New("environment variable is not allowed: " + name)
+		}
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errors.New("environment variable not set: " + name)
+	}
+	return value, nil
+}
+
+// secretPath returns the path of the secret file. The name could be
+// relative to the secrets directory or absolute path under it. Paths
+// escaping the directory, including via symbolic links, are refused.
+func secretPath(name string) (string, error) {
+	dir := envy.Get("HCU_SECRETS_DIR", "")
+	if dir == "" {
+		dir = DefaultSecretsDir
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Clean(dir))
+	if err != nil {
+		return "", errors.New("secrets directory is not available")
+	}
+	path := filepath.Clean(name)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.New("secret file is not on the secrets directory: " + name)
+	}
+	return path, nil
+}
@@ -0,0 +1,103 @@
+// Package secrets resolves secret references into actual secret values.
+//
+// A reference is a string with the "secret:" marker and a scheme prefix
+// such as
+//
+//	secret:file:softlayer-key
+//	secret:env:HCU_SECRET_SL_API_KEY
+//	secret:vault:secret/data/honcheonui/softlayer#api_key
+//
+// Files are read only from the secrets directory (HCU_SECRETS_DIR) and
+// environment variables should have HCU_SECRET_ prefix. The marker keeps
+// plain passwords which happen to start with a scheme, like "env:abc", as
+// they are.
+//
+// Provider credentials could be stored as references instead of actual
+// values and they are resolved at the moment they are handed to plugins.
+package secrets
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Resolver is an interface for secret backends.
+type Resolver interface {
+	// Resolve returns the secret value for the reference body, which is
+	// the part after the scheme prefix.
+	Resolve(ref string) (string, error)
+}
+
+// ResolverFunc is an adapter to use ordinary functions as Resolver.
+type ResolverFunc func(ref string) (string, error)
+
+// Resolve implements Resolver
+func (f ResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+// Marker is the prefix of secret references.
+const Marker = "secret:"
+
+// ErrUnknownScheme is returned when no resolver is registered for the scheme.
+var ErrUnknownScheme = errors.New("unknown secret scheme")
+
+var (
+	mu        sync.RWMutex
+	resolvers = map[string]Resolver{
+		"file":  ResolverFunc(resolveFile),
+		"env":   ResolverFunc(resolveEnv),
+		"vault": NewVaultResolverFromEnv(),
+	}
+)
+
+// Register adds or replaces the resolver for the scheme.
+func Register(scheme string, r Resolver) {
+	mu.Lock()
+	defer mu.Unlock()
+	resolvers[scheme] = r
+}
+
+// IsReference returns true if the value is a reference with the marker
+// and registered scheme. Other values including plain passwords starting
+// with a scheme are not references.
+func IsReference(value string) bool {
+	_, _, ok := lookup(value)
+	return ok
+}
+
+// Resolve returns the secret value of the reference. Values without the
+// marker are returned as they are.
+func Resolve(value string) (string, error) {
+	if !strings.HasPrefix(value, Marker) {
+		return value, nil
+	}
+	r, ref, ok := lookup(value)
+	if !ok {
+		return "", ErrUnknownScheme
+	}
+	secret, err := r.Resolve(ref)
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", errors.New("empty secret for " + value)
+	}
+	return secret, nil
+}
+
+func lookup(value string) (Resolver, string, bool) {
+	if !strings.HasPrefix(value, Marker) {
+		return nil, "", false
+	}
+	value = strings.TrimPrefix(value, Marker)
+	i := strings.Index(value, ":")
+	if i < 1 {
+		return nil, "", false
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	r, ok := resolvers[value[:i]]
+	return r, value[i+1:], ok
+}
@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Resolve_Plain(t *testing.T) {
+	r := require.New(t)
+
+	for _, value := range []string{
+		"password", "pass:word", ":password", "unknown:ref",
+		"file:/etc/passwd", "env:HOME", "vault:kv/softlayer",
+	} {
+		r.False(IsReference(value), value)
+		secret, err := Resolve(value)
+		r.NoError(err)
+		r.Equal(value, secret)
+	}
+}
+
+func Test_Resolve_FileAndEnv(t *testing.T) {
+	r := require.New(t)
+	dir, err := ioutil.TempDir("", "secrets")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	envy.Set("HCU_SECRETS_DIR", dir)
+	defer envy.Set("HCU_SECRETS_DIR", DefaultSecretsDir)
+
+	path := filepath.Join(dir, "key")
+	r.NoError(ioutil.WriteFile(path, []byte("file-secret\n"), 0600))
+	for _, ref := range []string{"secret:file:key", "secret:file:" + path} {
+		r.True(IsReference(ref), ref)
+		secret, err := Resolve(ref)
+		r.NoError(err, ref)
+		r.Equal("file-secret", secret)
+	}
+
+	_, err = Resolve("secret:file:missing")
+	r.Error(err)
+
+	// files out of the secrets directory are refused
+	outside, err := ioutil.TempFile("", "outside")
+	r.NoError(err)
+	defer os.Remove(outside.Name())
+	r.NoError(os.Symlink(outside.Name(), filepath.Join(dir, "link")))
+	for _, ref := range []string{
+		"secret:file:" + outside.Name(), "secret:file:../" + filepath.Base(outside.Name()),
+		"secret:file:link", "secret:file:", "secret:file:/",
+	} {
+		_, err = Resolve(ref)
+		r.Error(err, ref)
+	}
+
+	os.Setenv("HCU_SECRET_TEST", "env-secret")
+	defer os.Unsetenv("HCU_SECRET_TEST")
+	secret, err := Resolve("secret:env:HCU_SECRET_TEST")
+	r.NoError(err)
+	r.Equal("env-secret", secret)
+
+	_, err = Resolve("secret:env:HCU_SECRET_NO_SUCH")
+	r.Error(err)
+
+	// only prefixed variables are allowed, except the application key
+	os.Setenv("HCU_TEST_SECRET", "env-secret")
+	defer os.Unsetenv("HCU_TEST_SECRET")
+	for _, ref := range []string{"secret:env:HCU_TEST_SECRET", "secret:env:HCU_SECRET_KEY", "secret:env:HOME"} {
+		_, err = Resolve(ref)
+		r.Error(err, ref)
+	}
+
+	_, err = Resolve("secret:unknown:ref")
+	r.Equal(ErrUnknownScheme, err)
+}
+
+func Test_Resolve_Vault(t *testing.T) {
+	r := require.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch req.URL.Path {
+		case "/v1/secret/data/honcheonui/softlayer":
+			w.Write([]byte(`{"data": {"data": {"api_key": "kv2-secret"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/softlayer":
+			w.Write([]byte(`{"data": {"value": "kv1-secret", "count": 1}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	Register("vault", NewVaultResolver(server.URL, "token"))
+	defer Register("vault", NewVaultResolverFromEnv())
+
+	secret, err := Resolve("secret:vault:secret/data/honcheonui/softlayer#api_key")
+	r.NoError(err)
+	r.Equal("kv2-secret", secret)
+
+	secret, err = Resolve("secret:vault:kv/softlayer")
+	r.NoError(err)
+	r.Equal("kv1-secret", secret)
+
+	_, err = Resolve("secret:vault:kv/softlayer#count")
+	r.Error(err)
+	_, err = Resolve("secret:vault:kv/softlayer#missing")
+	r.Error(err)
+	_, err = Resolve("secret:vault:kv/missing")
+	r.Error(err)
+
+	Register("vault", NewVaultResolver(server.URL, "wrong"))
+	_, err = Resolve("secret:vault:kv/softlayer")
+	r.Error(err)
+}
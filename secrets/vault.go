@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/envy"
+)
+
+// VaultResolver resolves references from Vault compatible HTTP KV store.
+// The reference is formed as "path#key", for example
+// "secret/data/honcheonui/softlayer#api_key". Both of KV version 1 and 2
+// responses are supported.
+type VaultResolver struct {
+	Addr   string
+	Token  string
+	Client *http.Client
+}
+
+// NewVaultResolver returns new resolver for the vault server.
+func NewVaultResolver(addr, token string) *VaultResolver {
+	return &VaultResolver{
+		Addr:   strings.TrimRight(addr, "/"),
+		Token:  token,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewVaultResolverFromEnv returns new resolver configured with
+// HCU_VAULT_ADDR and HCU_VAULT_TOKEN environment variables.
+func NewVaultResolverFromEnv() *VaultResolver {
+	return NewVaultResolver(envy.Get("HCU_VAULT_ADDR", ""), envy.Get("HCU_VAULT_TOKEN", ""))
+}
+
+// Resolve implements Resolver
+func (v *VaultResolver) Resolve(ref string) (string, error) {
+	if v.Addr == "" {
+		return "", errors.New("vault address is not configured")
+	}
+	path, key := ref, "value"
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		path, key = ref[:i], ref[i+1:]
+	}
+
+	req, err := http.NewRequest(http.MethodGet, v.Addr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returns %v for %v", resp.Status, path)
+	}
+
+	body := struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	data := body.Data
+	if nested, ok := data["data"]; ok { // kv version 2
+		data = map[string]json.RawMessage{}
+		if err := json.Unmarshal(nested, &data); err != nil {
+			return "", err
+		}
+	}
+	raw, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("no key %v on %v", key, path)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("value of %v on %v is not a string", key, path)
+	}
+	return value, nil
+}
@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCron is returned when the cron expression could not be parsed.
+var ErrInvalidCron = errors.New("invalid cron expression")
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField is a bit set of allowed values of a field.
+type cronField uint64
+
+func (f cronField) has(n int) bool {
+	return f&(1<<uint(n)) != 0
+}
+
+// Cron is a parsed cron expression with five fields, minute, hour, day of
+// month, month, and day of week.
+type Cron struct {
+	Expr       string
+	minute     cronField
+	hour       cronField
+	dom        cronField
+	month      cronField
+	dow        cronField
+	anyDay     bool // day of month is not restricted
+	anyWeekday bool // day of week is not restricted
+}
+
+// ParseCron parses standard five fields cron expression. Each field could
+// be "*", a number, a range "a-b", a list "a,b", with optional step "/n".
+// Macros like "@daily" and "@hourly" are also supported.
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	spec := expr
+	if macro, ok := cronMacros[spec]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%v: %v requires 5 fields", ErrInvalidCron, expr)
+	}
+
+	c := &Cron{Expr: expr}
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if c.dow.has(7) {
+		c.dow |= 1 // 7 is also sunday
+	}
+	c.anyDay = fields[2] == "*"
+	c.anyWeekday = fields[4] == "*"
+	return c, nil
+}
+
+func parseCronField(field string, min, max int) (cronField, error) {
+	var f cronField
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("%v: invalid step %v", ErrInvalidCron, part)
+			}
+			rng, step = part[:i], n
+		}
+
+		from, to := min, max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			ends := strings.SplitN(rng, "-", 2)
+			a, err1 := strconv.Atoi(ends[0])
+			b, err2 := strconv.Atoi(ends[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("%v: invalid range %v", ErrInvalidCron, part)
+			}
+			from, to = a, b
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("%v: invalid value %v", ErrInvalidCron, part)
+			}
+			from, to = n, n
+			if step > 1 {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return 0, fmt.Errorf("%v: %v is out of range %v-%v", ErrInvalidCron, part, min, max)
+		}
+		for n := from; n <= to; n += step {
+			f |= 1 << uint(n)
+		}
+	}
+	return f, nil
+}
+
+// dayMatches returns true if the day matches. If both day of month and
+// day of week are restricted, one of them should be matched as cron does.
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom.has(t.Day())
+	dow := c.dow.has(int(t.Weekday()))
+	if c.anyDay || c.anyWeekday {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first time matches the expression after given time.
+// It returns zero time if there is no such time in five years.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		if !c.month.has(int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.hour.has(t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !c.minute.has(t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// String returns the expression
+func (c Cron) String() string {
+	return c.Expr
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseCron(t *testing.T) {
+	r := require.New(t)
+
+	for _, expr := range []string{
+		"* * * * *", "0 2 * * *", "*/15 * * * *", "0 9-18/3 * * 1-5",
+		"30 2 1,15 * *", "0 0 * * 7", "@hourly", " @daily ",
+	} {
+		_, err := ParseCron(expr)
+		r.NoError(err, expr)
+	}
+	for _, expr := range []string{
+		"", "* * * *", "60 * * * *", "0 24 * * *", "0 0 0 * *", "0 0 * 13 *",
+		"0 0 * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@often",
+	} {
+		_, err := ParseCron(expr)
+		r.Error(err, expr)
+	}
+}
+
+func Test_Cron_Next(t *testing.T) {
+	r := require.New(t)
+	base := time.Date(2026, 10, 18, 10, 17, 30, 0, time.UTC) // sunday
+
+	next := func(expr string) time.Time {
+		c, err := ParseCron(expr)
+		r.NoError(err, expr)
+		return c.Next(base)
+	}
+	r.Equal(time.Date(2026, 10, 18, 10, 18, 0, 0, time.UTC), next("* * * * *"))
+	r.Equal(time.Date(2026, 10, 18, 10, 30, 0, 0, time.UTC), next("*/15 * * * *"))
+	r.Equal(time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC), next("@hourly"))
+	r.Equal(time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC), next("0 2 * * *"))
+	r.Equal(time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), next("0 9-18/3 * * 1-5"))
+	r.Equal(time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC), next("0 0 * * 7"))
+	r.Equal(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), next("@monthly"))
+	r.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), next("@yearly"))
+	r.Equal(time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC), next("0 12 29 2 *"))
+
+	// day of month or day of week if both are restricted
+	r.Equal(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), next("0 0 1 * 1"))
+
+	// no such day
+	r.True(next("0 0 31 2 *").IsZero())
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+)
+
+// EncryptedPrefix is a prefix of encrypted string. It marks the value is
+// encrypted and the version of encryption scheme.
+const EncryptedPrefix = "enc:v1:"
+
+// errors for encryption
+var (
+	ErrNoKey         = errors.New("no encryption key")
+	ErrNotEncrypted  = errors.New("not encrypted")
+	ErrMalformedData = errors.New("malformed encrypted data")
+)
+
+// IsEncrypted returns true if the string is encrypted by Encrypt.
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, EncryptedPrefix)
+}
+
+// Encrypt encrypts the plain text with AES-256-GCM and returns it as a
+// prefixed base64 string. The key could be any non-empty string and the
+// actual key is derived from it.
+func Encrypt(key, plain string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts the string encrypted by Encrypt with the same key.
+func Decrypt(key, encrypted string) (string, error) {
+	if !IsEncrypted(encrypted) {
+		return "", ErrNotEncrypted
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, EncryptedPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrMalformedData
+	}
+	nonce, data := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func newAEAD(key string) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, ErrNoKey
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
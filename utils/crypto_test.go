@@ -0,0 +1,44 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/hyeoncheon/honcheonui/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EncryptDecrypt(t *testing.T) {
+	r := require.New(t)
+
+	enc, err := utils.Encrypt("secret-key", "password")
+	r.NoError(err)
+	r.True(utils.IsEncrypted(enc))
+	r.NotContains(enc, "password")
+
+	enc2, err := utils.Encrypt("secret-key", "password")
+	r.NoError(err)
+	r.NotEqual(enc, enc2, "nonce should be random")
+
+	plain, err := utils.Decrypt("secret-key", enc)
+	r.NoError(err)
+	r.Equal("password", plain)
+
+	_, err = utils.Decrypt("another-key", enc)
+	r.Error(err)
+}
+
+func Test_EncryptDecrypt_Errors(t *testing.T) {
+	r := require.New(t)
+
+	_, err := utils.Encrypt("", "password")
+	r.Equal(utils.ErrNoKey, err)
+
+	_, err = utils.Decrypt("secret-key", "password")
+	r.Equal(utils.ErrNotEncrypted, err)
+
+	_, err = utils.Decrypt("secret-key", utils.EncryptedPrefix+"!!!")
+	r.Equal(utils.ErrMalformedData, err)
+
+	_, err = utils.Decrypt("secret-key", utils.EncryptedPrefix+"AAAA")
+	r.Equal(utils.ErrMalformedData, err)
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/envy"
+)
+
+// DurationFromEnv returns duration configured by the environment variable
+// or given default value if it is not set. If the value is invalid, the
+// default value is returned with an error describing it.
+func DurationFromEnv(key string, def time.Duration) (time.Duration, error) {
+	value := envy.Get(key, "")
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return def, fmt.Errorf("invalid duration %v for %v", value, key)
+	}
+	return d, nil
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DurationFromEnv(t *testing.T) {
+	r := require.New(t)
+	key := "HCU_TEST_DURATION"
+
+	envy.Set(key, "")
+	d, err := DurationFromEnv(key, time.Minute)
+	r.NoError(err)
+	r.Equal(time.Minute, d)
+
+	envy.Set(key, "90s")
+	d, err = DurationFromEnv(key, time.Minute)
+	r.NoError(err)
+	r.Equal(90*time.Second, d)
+
+	envy.Set(key, "soon")
+	d, err = DurationFromEnv(key, time.Minute)
+	r.Error(err)
+	r.Equal(time.Minute, d)
+	envy.Set(key, "")
+}
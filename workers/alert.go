@@ -0,0 +1,65 @@
+package workers
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/hyeoncheon/honcheonui/models"
+	"github.com/hyeoncheon/honcheonui/plugins"
+)
+
+// raiseAlerts evaluates alert rules on events detected by resource sync and
+// raises an incident for each matched rule and event. Raised incidents are
+// linked with the resource, correlated, and sent to notifiers like
+// incidents from providers.
+func raiseAlerts(events models.ResourceEvents) {
+	if len(events) == 0 {
+		return
+	}
+	rules, err := models.AllAlertRules()
+	if err != nil {
+		logger.Errorf("could not get alert rules: %v", err)
+		return
+	}
+	if len(*rules) == 0 {
+		return
+	}
+	correlations, err := models.AllCorrelationRules()
+	if err != nil {
+		logger.Errorf("could not get correlation rules. skip correlation: %v", err)
+	}
+
+	for _, e := range events {
+		res := &models.Resource{}
+		if err := models.DB.Eager("Tags").Find(res, e.ResourceID); err != nil {
+			logger.Errorf("could not find resource %v: %v", e.ResourceID, err)
+			continue
+		}
+		tagIDs := []uuid.UUID{}
+		for _, t := range res.Tags {
+			tagIDs = append(tagIDs, t.ID)
+		}
+		serviceIDs := []uuid.UUID{}
+		for _, s := range *res.Services() {
+			serviceIDs = append(serviceIDs, s.ID)
+		}
+
+		for _, rule := range *rules {
+			if !rule.Matches(e, tagIDs, serviceIDs) {
+				continue
+			}
+			inci := rule.Incident(e, res, time.Now())
+			if _, _, err := inci.Upsert(); err != nil {
+				logger.Errorf("could not raise incident by alert rule %v: %v", rule, err)
+				continue
+			}
+			logger.Infof("alert rule %v raised incident %v for %v", rule, inci.ID, res)
+			inci.LinkResource(res)
+			if _, err := inci.Correlate(*correlations); err != nil {
+				logger.Errorf("could not correlate incident %v: %v", inci.ID, err)
+			}
+			notifyIncident(plugins.EventIncidentOpened, inci)
+		}
+	}
+}
@@ -2,17 +2,39 @@ package workers
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v5"
+	glogger "github.com/gobuffalo/logger"
+	"github.com/gofrs/uuid"
+
+	"github.com/hyeoncheon/honcheonui/models"
+	"github.com/hyeoncheon/honcheonui/utils"
 )
 
 // constants
 const (
-	DefaultQueue = "default"
+	DefaultQueue       = "default"
+	DefaultConcurrency = 4
 )
 
+// scheduling modes of workers
+const (
+	ModeEnabled  = "enabled"
+	ModeManual   = "manual"
+	ModeDisabled = "disabled"
+)
+
+// ErrDisabled is returned when a disabled worker is queued.
+var ErrDisabled = errors.New("worker is disabled")
+
 // HandlerHolder is an interface for workers.
 type HandlerHolder interface {
 	Reset() error
@@ -26,16 +48,28 @@ type Worker struct {
 	IsPeriodic   bool
 	InitailDelay time.Duration
 	RunPeriod    time.Duration
+	Schedule     string
+	Mode         string
 	LastQueuedAt time.Time
 	CountQueued  int32
+	Retry        RetryPolicy
+	running      int32
+	pending      int32
+	repeaterArgs worker.Args
+	repeaterAt   time.Time
+	mutex        sync.Mutex
 }
 
 // Workers is a search map for the workers with its name.
 type Workers map[string]*Worker
 
 var workers = Workers{}
-var logger buffalo.Logger
+var logger buffalo.Logger = glogger.NewLogger("Debug").WithField("category", "worker")
 var aw worker.Worker
+var env string
+
+// slots limits the number of provider jobs running at the same time.
+var slots chan struct{}
 
 //*** initiators
 
@@ -47,44 +81,80 @@ func init() {
 }
 
 // InitWorkers registers all application workers after system workers.
+// Handlers of all workers are registered, including disabled ones, so
+// jobs already queued are handled. The scheduling mode only decides
+// whether periodic workers are scheduled and whether workers could be
+// queued.
 func InitWorkers(app *buffalo.App) error {
 	logger = app.Logger.WithField("category", "worker")
 	aw = app.Worker
-	logger.Infof("register workers...")
+	env = app.Env
+	slots = make(chan struct{}, concurrency())
+	logger.Infof("register workers... (concurrency: %v)", cap(slots))
 
 	for name, wkr := range workers {
 		logger.Debugf("---> worker: %v %v", name, wkr)
+		wkr.Mode = workerMode(name, defaultMode(env))
 		if err := wkr.Reset(); err != nil {
 			logger.Errorf("could not initialize worker %v: %v", name, err)
 			continue
 		}
-		if err := aw.Register(name, wkr.Handler); err != nil {
+		if err := aw.Register(name, wkr.perform); err != nil {
 			logger.Errorf("could not register worker %v: %v", name, err)
 			continue
 		}
-		if app.Env != "production" {
-			logger.Infof("run in %v mode. skip periodic job queuing for %v", app.Env, name)
+		if !wkr.IsPeriodic {
 			continue
 		}
-		if wkr.IsPeriodic && wkr.RunPeriod >= (5*time.Second) {
-			logger.Infof("%v is periodic worker. initial queuing...", name)
-			err := Queue(
-				workerRepeater,
-				map[string]interface{}{
-					"worker": wkr.Name,
-					"args":   worker.Args{},
-					"repeat": wkr.RunPeriod,
-				},
-				wkr.InitailDelay,
-			)
-			if err != nil {
-				logger.Errorf("oops! could not add a queue for %v: %v", name, err)
-			}
+		if wkr.Mode != ModeEnabled {
+			logger.Infof("%v is %v in %v mode. skip scheduling", name, wkr.Mode, env)
+			continue
 		}
+		wkr.startScheduling()
 	}
 	return nil
 }
 
+// StartScheduler starts scheduling of periodic workers as if the default
+// scheduling mode is enabled, so the scheduler could be run in any
+// environment. Modes configured for each worker are kept.
+func StartScheduler() {
+	logger.Infof("start scheduler in %v mode", env)
+	for name, wkr := range workers {
+		if !wkr.IsPeriodic || wkr.Mode == ModeEnabled || wkr.Mode == ModeDisabled {
+			continue
+		}
+		if wkr.Mode = workerMode(name, ModeEnabled); wkr.Mode == ModeEnabled {
+			wkr.startScheduling()
+		}
+	}
+}
+
+// startScheduling queues the repeater for the periodic worker.
+func (w *Worker) startScheduling() {
+	if w.Schedule != "" {
+		logger.Infof("%v is scheduled worker (%v). start scheduling...", w.Name, w.Schedule)
+		err := Queue(workerRepeater, map[string]interface{}{"worker": w.Name}, 0)
+		if err != nil {
+			logger.Errorf("oops! could not add a queue for %v: %v", w.Name, err)
+		}
+	} else if w.RunPeriod >= (5 * time.Second) {
+		logger.Infof("%v is periodic worker. initial queuing...", w.Name)
+		err := Queue(
+			workerRepeater,
+			map[string]interface{}{
+				"worker": w.Name,
+				"args":   worker.Args{},
+				"repeat": w.RunPeriod,
+			},
+			w.InitailDelay,
+		)
+		if err != nil {
+			logger.Errorf("oops! could not add a queue for %v: %v", w.Name, err)
+		}
+	}
+}
+
 //*** helper functions
 
 // Run runs the worker immediately
@@ -94,18 +164,175 @@ func Run(name string, args worker.Args) error {
 
 // Queue enqueues the worker after given delay
 func Queue(name string, args worker.Args, delay time.Duration) error {
+	return queue(nil, name, args, delay)
+}
+
+// QueueTx enqueues the worker after given delay within the transaction if
+// the queue is persistent, so the job is queued only if the transaction is
+// committed. Otherwise it is the same as Queue.
+func QueueTx(tx *pop.Connection, name string, args worker.Args, delay time.Duration) error {
+	return queue(tx, name, args, delay)
+}
+
+func queue(tx *pop.Connection, name string, args worker.Args, delay time.Duration) error {
 	w := workers[name]
 	if w == nil {
 		return errors.New("could not find worker")
 	}
+	if w.Mode == ModeDisabled {
+		return ErrDisabled
+	}
+	w.mutex.Lock()
 	w.CountQueued++
 	w.LastQueuedAt = time.Now()
+	if !isPersistent() {
+		w.pending++
+	}
+	w.mutex.Unlock()
+	if name == workerRepeater {
+		// kept for the status. copied since the repeater changes its args
+		if target := workers[fmt.Sprint(args["worker"])]; target != nil {
+			copied := worker.Args{}
+			for k, v := range args {
+				copied[k] = v
+			}
+			target.mutex.Lock()
+			target.repeaterArgs = copied
+			target.repeaterAt = time.Now().Add(delay)
+			target.mutex.Unlock()
+		}
+	}
 
-	return aw.PerformIn(worker.Job{
+	job := worker.Job{
 		Queue:   DefaultQueue,
 		Handler: name,
 		Args:    args,
-	}, delay)
+	}
+	if dw, ok := aw.(*DBWorker); ok && tx != nil {
+		return dw.performAt(tx, job, time.Now().Add(delay))
+	}
+	return aw.PerformIn(job, delay)
+}
+
+// durationFromEnv returns duration configured by the environment variable
+// or given default value if it is not set or invalid.
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	d, err := utils.DurationFromEnv(key, def)
+	if err != nil {
+		logger.Warnf("%v. use default %v", err, def)
+	}
+	return d
+}
+
+// defaultMode returns the default scheduling mode configured by
+// HCU_SCHEDULER. Periodic workers are enabled in production and
+// manual-only in other environments if it is not set.
+func defaultMode(env string) string {
+	mode := envy.Get("HCU_SCHEDULER", "")
+	if mode == "" && env == "production" {
+		return ModeEnabled
+	}
+	if mode == "" {
+		return ModeManual
+	}
+	if !isMode(mode) {
+		logger.Warnf("invalid scheduling mode %v. use %v", mode, ModeManual)
+		return ModeManual
+	}
+	return mode
+}
+
+// workerMode returns the scheduling mode of the worker configured by
+// HCU_SCHEDULER_WORKERS or given default mode. System workers are always
+// enabled.
+func workerMode(name, def string) string {
+	if name == workerRepeater {
+		return ModeEnabled
+	}
+	return modeFor(name, envy.Get("HCU_SCHEDULER_WORKERS", ""), def)
+}
+
+// modeFor returns the mode of the worker from the list of name=mode pairs
+// separated by comma, such as "worker.ResourceSync=enabled". The prefix
+// "worker." of names could be omitted.
+func modeFor(name, list, def string) string {
+	for _, pair := range strings.Split(list, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, mode := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if key != name && "worker."+key != name {
+			continue
+		}
+		if !isMode(mode) {
+			logger.Warnf("invalid scheduling mode %v for %v. use %v", mode, name, def)
+			return def
+		}
+		return mode
+	}
+	return def
+}
+
+func isMode(mode string) bool {
+	return mode == ModeEnabled || mode == ModeManual || mode == ModeDisabled
+}
+
+// concurrency returns the number of provider jobs could be run at the same
+// time, configured by HCU_SYNC_CONCURRENCY.
+func concurrency() int {
+	value := envy.Get("HCU_SYNC_CONCURRENCY", "")
+	if value == "" {
+		return DefaultConcurrency
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		logger.Warnf("invalid concurrency %v. use default %v", value, DefaultConcurrency)
+		return DefaultConcurrency
+	}
+	return n
+}
+
+// acquire blocks until a slot for a provider job is available and returns
+// a function to release it.
+func acquire() func() {
+	if slots == nil {
+		return func() {}
+	}
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// fanOut queues a job of the worker for each provider so a slow provider
+// does not delay the others. Scheduled fan-out skips providers have their
+// own schedules since they are queued by their schedules.
+func fanOut(name string, scheduled bool) error {
+	providers := &models.Providers{}
+	if err := models.DB.All(providers); err != nil {
+		logger.Errorf("database error: %v", err)
+		return err
+	}
+	overridden := map[uuid.UUID]bool{}
+	if scheduled {
+		schedules, err := models.AllSchedules()
+		if err != nil {
+			logger.Errorf("could not get schedules: %v", err)
+		}
+		for _, s := range schedules.Overrides(name) {
+			overridden[s.ProviderID] = true
+		}
+	}
+	logger.Infof("queue %v for %v providers", name, len(*providers)-len(overridden))
+	for _, provider := range *providers {
+		if overridden[provider.ID] {
+			continue
+		}
+		err := Run(name, worker.Args{"provider_id": provider.ID.String()})
+		if err != nil {
+			logger.Errorf("could not queue %v for %v: %v", name, provider, err)
+		}
+	}
+	return nil
 }
 
 // RegisterWorkers adds given workers into workers map.
@@ -123,7 +350,9 @@ const workerRepeater = "worker.Repeater"
 
 type repeater struct{}
 
-// repeaterHandler is simple system worker handles periodic jobs.
+// repeaterHandler is simple system worker handles periodic jobs. For the
+// scheduled workers, it checks the schedules every tick and runs due jobs.
+// The state of the schedules is carried by the repeater job itself.
 func (r repeater) Handler(wa worker.Args) error {
 	logger.Debugf("------ cron handler invoked with %v", wa)
 	name, ok := wa["worker"].(string)
@@ -131,14 +360,27 @@ func (r repeater) Handler(wa worker.Args) error {
 		logger.Errorf("could not cast worker name: %v", wa["worker"])
 		return errors.New("could not cast worker name")
 	}
+	if w := workers[name]; w != nil && w.Schedule != "" {
+		state, _ := wa[argState].(map[string]interface{})
+		wa[argState] = w.runScheduled(time.Now(), state)
+		return Queue(workerRepeater, wa, schedulerTick)
+	}
 	repeat, ok := wa["repeat"].(time.Duration)
+	if n, isNumber := wa["repeat"].(float64); isNumber { // from persistent queue
+		repeat, ok = time.Duration(n), true
+	}
 	if !ok {
 		logger.Errorf("could not cast worker repeat: %v", wa["repeat"])
 		return errors.New("could not cast worker repeat")
 	}
-	args := wa["args"].(worker.Args)
+	args, ok := wa["args"].(worker.Args)
+	if !ok {
+		args, _ = wa["args"].(map[string]interface{})
+	}
 	logger.Debugf("------ run %v and queue new instance...", name)
-	Run(name, args)
+	if err := Run(name, args); err != nil {
+		logger.Errorf("could not run %v: %v", name, err)
+	}
 	return Queue(workerRepeater, wa, repeat)
 }
 
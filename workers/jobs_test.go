@@ -0,0 +1,73 @@
+package workers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/envy"
+	glogger "github.com/gobuffalo/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingWorker records registered handlers and queued jobs.
+type recordingWorker struct {
+	handlers map[string]worker.Handler
+	queued   []worker.Job
+}
+
+func (w *recordingWorker) Start(context.Context) error { return nil }
+func (w *recordingWorker) Stop() error                 { return nil }
+func (w *recordingWorker) Perform(job worker.Job) error {
+	return w.PerformIn(job, 0)
+}
+func (w *recordingWorker) PerformAt(job worker.Job, t time.Time) error {
+	return w.PerformIn(job, time.Until(t))
+}
+func (w *recordingWorker) PerformIn(job worker.Job, d time.Duration) error {
+	w.queued = append(w.queued, job)
+	return nil
+}
+func (w *recordingWorker) Register(name string, h worker.Handler) error {
+	w.handlers[name] = h
+	return nil
+}
+
+func Test_InitWorkers_Modes(t *testing.T) {
+	r := require.New(t)
+
+	savedWorkers, savedBackend := workers, aw
+	defer func() { workers, aw = savedWorkers, savedBackend }()
+	workers = Workers{}
+	RegisterWorkers(
+		&Worker{HandlerHolder: &repeater{}, Name: workerRepeater},
+		&Worker{HandlerHolder: &testHandler{}, Name: "worker.Enabled", IsPeriodic: true, Schedule: "0 2 * * *"},
+		&Worker{HandlerHolder: &testHandler{}, Name: "worker.Manual", IsPeriodic: true, Schedule: "0 3 * * *"},
+		&Worker{HandlerHolder: &testHandler{}, Name: "worker.Disabled", IsPeriodic: true, Schedule: "0 4 * * *"},
+	)
+	envy.Set("HCU_SCHEDULER", ModeManual)
+	envy.Set("HCU_SCHEDULER_WORKERS", "Enabled=enabled,Disabled=disabled")
+	defer envy.Set("HCU_SCHEDULER", "")
+	defer envy.Set("HCU_SCHEDULER_WORKERS", "")
+
+	rec := &recordingWorker{handlers: map[string]worker.Handler{}}
+	app := &buffalo.App{Options: buffalo.Options{Env: "test", Worker: rec, Logger: glogger.NewLogger("Debug")}}
+	r.NoError(InitWorkers(app))
+
+	// handlers of all workers are registered regardless of the mode
+	for name := range workers {
+		r.NotNil(rec.handlers[name], name)
+	}
+
+	// only enabled workers are scheduled
+	r.Len(rec.queued, 1)
+	r.Equal(workerRepeater, rec.queued[0].Handler)
+	r.Equal("worker.Enabled", rec.queued[0].Args["worker"])
+
+	// disabled workers could not be queued
+	r.NoError(Run("worker.Manual", nil))
+	r.Equal(ErrDisabled, Run("worker.Disabled", nil))
+	r.Len(rec.queued, 2)
+}
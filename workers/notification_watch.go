@@ -2,9 +2,13 @@ package workers
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/validate/v3"
 	"github.com/hyeoncheon/spec"
 	"github.com/jinzhu/copier"
 
@@ -16,9 +20,9 @@ import (
 
 // constants belongs to this worker
 const (
-	WorkerNotificationWatch             = "worker.NotificationWatch"
-	workerNotificationWatchInitailDelay = 30 * time.Second
-	workerNotificationWatchRunPeriod    = 24 * time.Hour
+	WorkerNotificationWatch         = "worker.NotificationWatch"
+	workerNotificationWatchSchedule = "0 1 * * *"
+	defaultNotificationLookback     = 150 * 24 * time.Hour
 )
 
 // NotificationWatch is worker to sync resources via plugin in batch mode.
@@ -29,17 +33,17 @@ func init() {
 		HandlerHolder: &NotificationWatch{},
 		Name:          WorkerNotificationWatch,
 		IsPeriodic:    true,
-		InitailDelay:  workerNotificationWatchInitailDelay,
-		RunPeriod:     workerNotificationWatchRunPeriod,
+		Schedule:      workerNotificationWatchSchedule,
 		LastQueuedAt:  time.Time{},
 		CountQueued:   0,
+		Retry:         DefaultRetryPolicy,
 	})
 }
 
 // Handler implements HandlerHolder
 func (j NotificationWatch) Handler(args worker.Args) error {
 	providerID := args["provider_id"]
-	return watchNotification(providerID)
+	return watchNotification(providerID, args[argScheduled] == true)
 }
 
 // Reset implements HandlerHolder
@@ -49,62 +53,163 @@ func (j NotificationWatch) Reset() error {
 
 //*** local task functions
 
-func watchNotification(id interface{}) error {
-	providers := &models.Providers{}
-	query := models.DB.Q()
-	if id != nil {
-		logger.Errorf("single mode")
-		query = query.Where("id = ?", id)
+// watchNotification queues a job for each provider if id is not given, or
+// syncs notifications of the provider.
+func watchNotification(id interface{}, scheduled bool) error {
+	if id == nil {
+		return fanOut(WorkerNotificationWatch, scheduled)
 	}
-	if err := query.All(providers); err != nil {
-		logger.Errorf("database error: %v", err)
+	provider := &models.Provider{}
+	if err := models.DB.Find(provider, id); err != nil {
+		logger.Errorf("could not find provider %v: %v", id, err)
 		return err
 	}
-	logger.Infof("watch notifications via plugins for %v", *providers)
-
+	release := acquire()
+	defer release()
 	// TODO: use brand account without credential loop
-	for _, provider := range *providers {
-		logger.Debugf("watch notifications for %v...", provider)
+	logger.Debugf("watch notifications for %v...", provider)
 
-		plugin, err := plugins.GetPlugin(provider.Provider, "provider")
-		if err != nil {
-			logger.Errorf("could not find plugin %v: %v", provider.Provider, err)
+	run := models.StartSyncRun(provider.ID, models.SyncNotification)
+	err := watchProviderNotification(provider, run)
+	if err := run.Finish(err); err != nil {
+		logger.Errorf("could not save sync run: %v", err)
+	}
+	if err != nil {
+		notifySyncFailed(provider, "notification", err)
+		return err
+	}
+	logger.Debugf("notifications for %v are synced successfully (%v)", provider, run.Total)
+	return nil
+}
+
+// watchProviderNotification syncs notifications of the provider as
+// incidents and counts them on the run.
+func watchProviderNotification(provider *models.Provider, run *models.SyncRun) error {
+	plugin, err := plugins.GetPlugin(provider.Provider, "provider", plugins.CapNotifications)
+	if err != nil {
+		logger.Errorf("could not find plugin %v: %v", provider.Provider, err)
+		return err
+	}
+	user, pass, err := provider.Credentials()
+	if err != nil {
+		logger.Errorf("could not get credentials of %v: %v", provider, err)
+		return err
+	}
+	since := provider.NotificationsSince(time.Now(), notificationLookback())
+	notes, err := plugin.GetNotifications(user, pass, since)
+	if err != nil {
+		logger.Errorf("could not get notifications via plugin: %v", err)
+		return err
+	}
+	logger.Debugf("got %v notifications since %v. create/update...", len(notes), since)
+
+	rules, err := models.AllCorrelationRules()
+	if err != nil {
+		logger.Errorf("could not get correlation rules. skip correlation: %v", err)
+	}
+
+	// notifications which could never be saved are skipped, and the mark is
+	// held at the oldest one failed for a reason worth retrying.
+	mark := since
+	hold := time.Time{}
+	failed := 0
+	for _, n := range notes {
+		note, ok := n.(spec.HoncheonuiNotification)
+		if !ok {
+			logger.Warnf("unrecognized data format: %T. skipped", n)
 			continue
 		}
-		since := time.Now().AddDate(0, -5, 0)
-		notes, err := plugin.GetNotifications(provider.User, provider.Pass, since)
-		if err != nil {
-			logger.Errorf("could not get notifications via plugin: %v", err)
+		if jb, err := json.Marshal(note); err == nil {
+			logger.Debugf("------ found: %v", string(jb))
 		}
-		logger.Debugf("got %v notifications. create/update...", len(notes))
 
-		//var ids []uuid.UUID
-		for _, n := range notes {
-			note, ok := n.(spec.HoncheonuiNotification)
-			if !ok {
-				logger.Errorf("unrecognized data format: %T", n)
-			}
-			if jb, err := json.Marshal(note); err == nil {
-				logger.Debugf("------ found: %v", string(jb))
-			}
-
-			inci := &models.Incident{}
-			if err := copier.Copy(inci, note); err != nil {
-				logger.Errorf("object copying error for %v", note)
-				continue
-			}
-			if err := inci.Save(); err != nil {
-				logger.Errorf("could not save incident record: %v", err)
+		inci := &models.Incident{}
+		if err := copier.Copy(inci, note); err != nil {
+			logger.Warnf("object copying error for %v. skipped", note)
+			continue
+		}
+		existing, changed, err := inci.Upsert()
+		if verrs, ok := err.(*validate.Errors); ok {
+			logger.Warnf("invalid notification %v: %v. skipped", note.OriginalID, verrs)
+			continue
+		}
+		if err != nil {
+			logger.Errorf("could not save incident record: %v", err)
+			failed++
+			if hold.IsZero() || note.ModifiedAt.Before(hold) {
+				hold = note.ModifiedAt
 			}
+			continue
+		}
+		run.Total++
+		if inci.ModifiedAt.After(mark) {
+			mark = inci.ModifiedAt
+		}
+		if !changed {
+			continue
+		}
+		if existing == nil {
+			run.Added++
+		} else {
+			run.Modified++
+		}
+		if existing == nil && inci.IsOpen {
+			notifyIncident(plugins.EventIncidentOpened, inci)
+		} else if existing != nil && existing.IsOpen && !inci.IsOpen {
+			notifyIncident(plugins.EventIncidentClosed, inci)
+		}
 
-			inci.LinkResourcesByOrigIDs(note.ResourceIDs...)
-			inci.LinkUsers(note.UserIDs...)
-			if jb, err := json.Marshal(inci); err == nil {
-				logger.Debugf("------ note: %v", string(jb))
+		inci.LinkResourcesByOrigIDs(note.ResourceIDs...)
+		inci.LinkUsers(note.UserIDs...)
+		if existing == nil {
+			if parent, err := inci.Correlate(*rules); err != nil {
+				logger.Errorf("could not correlate incident %v: %v", inci.ID, err)
+			} else if parent != nil {
+				logger.Infof("incident %v is grouped into %v", inci.ID, parent.ID)
 			}
 		}
+		if jb, err := json.Marshal(inci); err == nil {
+			logger.Debugf("------ note: %v", string(jb))
+		}
+	}
 
-		logger.Debugf("resources for %v are synced successfully (%v)", provider, len(notes))
+	if !hold.IsZero() && hold.Before(mark) {
+		mark = hold
+	}
+	if err := provider.SetNotificationMark(mark); err != nil {
+		logger.Errorf("could not save notification mark of %v: %v", provider, err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("could not save %v of %v notifications", failed, len(notes))
 	}
 	return nil
 }
+
+// notificationLookback returns how far notifications are fetched back for
+// providers never watched or watched long ago.
+func notificationLookback() time.Duration {
+	return durationFromEnv("HCU_NOTIFICATION_LOOKBACK", defaultNotificationLookback)
+}
+
+// notifyIncident sends incident event to notifiers.
+func notifyIncident(event string, inci *models.Incident) {
+	subject := fmt.Sprintf("[%v] %v", event, inci.Title)
+	plugins.Notify(event, subject, inci.Content, map[string]string{
+		"provider":  inci.Provider,
+		"category":  inci.Category,
+		"code":      strconv.Itoa(inci.Code),
+		"issued_by": inci.IssuedBy,
+		"issued_at": inci.IssuedAt.Format(time.RFC3339),
+		"url":       envy.Get("HCU_URL", "") + "/incidents/" + inci.ID.String(),
+	})
+}
+
+// notifySyncFailed sends sync failure event to notifiers.
+func notifySyncFailed(provider *models.Provider, kind string, err error) {
+	subject := fmt.Sprintf("[%v] %v sync failed for %v", plugins.EventSyncFailed, kind, provider)
+	plugins.Notify(plugins.EventSyncFailed, subject, err.Error(), map[string]string{
+		"provider":    provider.Provider,
+		"provider_id": provider.ID.String(),
+		"kind":        kind,
+	})
+}
@@ -0,0 +1,43 @@
+package workers
+
+import (
+	"os"
+	"time"
+
+	"github.com/gobuffalo/envy"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+func (ms *ModelSuite) Test_WatchNotification_Fake() {
+	envy.Set("HCU_NOTIFICATION_LOOKBACK", "87600h")
+	defer envy.Set("HCU_NOTIFICATION_LOOKBACK", "")
+	provider := ms.fakeProvider()
+
+	// malformed notifications are skipped and do not hold the mark
+	ms.NoError(watchNotification(provider.ID, false))
+	count, err := ms.DB.Count(&models.Incidents{})
+	ms.NoError(err)
+	ms.Equal(2, count)
+	mark := time.Date(2026, 6, 10, 0, 0, 0, 0, time.UTC)
+	ms.True(mark.Equal(provider.NotificationMark()))
+
+	run := provider.LastSyncRun(models.SyncNotification)
+	ms.NotNil(run)
+	ms.False(run.IsFailed())
+	ms.Equal(2, run.Total)
+
+	fixture := ms.fakeFixture(4)
+	defer os.Remove(fixture)
+	ms.useFake(fixture)
+	ms.NoError(watchNotification(provider.ID, false))
+	count, err = ms.DB.Count(&models.Incidents{})
+	ms.NoError(err)
+	ms.Equal(2, count)
+	ms.True(mark.Equal(provider.NotificationMark()))
+
+	inci := &models.Incident{}
+	ms.NoError(ms.DB.Where("original_id = ?", "9001").First(inci))
+	ms.NoError(ms.DB.Load(inci, "Events"))
+	ms.NotEmpty(inci.Events)
+}
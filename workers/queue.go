@@ -0,0 +1,213 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+// defaults for the database backed queue
+const (
+	DefaultLease        = 5 * time.Minute
+	DefaultPollInterval = 5 * time.Second
+	defaultClaimLimit   = 10
+)
+
+// DBWorker is an implementation of worker.Worker backed by the jobs table.
+// Queued jobs survive restarts, and each job is run by only one instance
+// holding the lease of the job. The lease is extended while the job runs
+// and the job could be claimed again if the instance is gone.
+type DBWorker struct {
+	Owner        string
+	Lease        time.Duration
+	PollInterval time.Duration
+	handlers     map[string]worker.Handler
+	mutex        sync.RWMutex
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// NewBackend returns the worker backend configured by HCU_WORKER_BACKEND.
+// It returns nil for the default in-memory worker of buffalo.
+func NewBackend() worker.Worker {
+	switch backend := envy.Get("HCU_WORKER_BACKEND", "memory"); backend {
+	case "database":
+		return NewDBWorker()
+	case "memory":
+		return nil
+	default:
+		fmt.Fprintf(os.Stderr, "unknown worker backend %v. use memory\n", backend)
+		return nil
+	}
+}
+
+// NewDBWorker returns a database backed worker.
+func NewDBWorker() *DBWorker {
+	host, _ := os.Hostname()
+	return &DBWorker{
+		Owner:        fmt.Sprintf("%v-%v-%v", host, os.Getpid(), uuid.Must(uuid.NewV4()).String()[:8]),
+		Lease:        durationFromEnv("HCU_WORKER_LEASE", DefaultLease),
+		PollInterval: durationFromEnv("HCU_WORKER_POLL_INTERVAL", DefaultPollInterval),
+		handlers:     map[string]worker.Handler{},
+	}
+}
+
+// Register implements worker.Worker
+func (w *DBWorker) Register(name string, h worker.Handler) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if _, ok := w.handlers[name]; ok {
+		return fmt.Errorf("handler already mapped for name %v", name)
+	}
+	w.handlers[name] = h
+	return nil
+}
+
+// Perform implements worker.Worker
+func (w *DBWorker) Perform(job worker.Job) error {
+	return w.PerformAt(job, time.Now())
+}
+
+// PerformIn implements worker.Worker
+func (w *DBWorker) PerformIn(job worker.Job, d time.Duration) error {
+	return w.PerformAt(job, time.Now().Add(d))
+}
+
+// PerformAt implements worker.Worker. The same job already pending is not
+// queued again.
+func (w *DBWorker) PerformAt(job worker.Job, t time.Time) error {
+	return w.performAt(models.DB, job, t)
+}
+
+// performAt stores the job within the transaction.
+func (w *DBWorker) performAt(tx *pop.Connection, job worker.Job, t time.Time) error {
+	j := models.NewJob(job.Queue, job.Handler, job.Args, t)
+	if job.Handler == workerRepeater {
+		// a repeater chain is unique per worker regardless of its state
+		j.SetUniqueKey(job.Queue, job.Handler, fmt.Sprint(job.Args["worker"]))
+	}
+	queued, err := j.Enqueue(tx)
+	if err != nil {
+		return err
+	}
+	if !queued {
+		logger.Debugf("job %v %v is already queued. skipped", job.Handler, j.Args)
+	}
+	return nil
+}
+
+// Start implements worker.Worker. It polls due jobs and runs them until
+// the context is done or the worker is stopped.
+func (w *DBWorker) Start(ctx context.Context) error {
+	ctx, w.cancel = context.WithCancel(ctx)
+	logger.Infof("starting database worker %v (lease %v)", w.Owner, w.Lease)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.PollInterval)
+		defer ticker.Stop()
+		for {
+			w.poll(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop implements worker.Worker. It waits for running jobs.
+func (w *DBWorker) Stop() error {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+	logger.Infof("database worker %v was stopped", w.Owner)
+	return nil
+}
+
+// poll claims due jobs of registered handlers and runs them.
+func (w *DBWorker) poll(ctx context.Context) {
+	jobs, err := models.ClaimJobs(w.Owner, w.Lease, defaultClaimLimit, w.handlerNames())
+	if err != nil {
+		logger.Errorf("could not claim jobs: %v", err)
+	}
+	for i := range jobs {
+		w.wg.Add(1)
+		go func(j *models.Job) {
+			defer w.wg.Done()
+			w.run(ctx, j)
+		}(&jobs[i])
+	}
+}
+
+// handlerNames returns names of registered handlers.
+func (w *DBWorker) handlerNames() []string {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	names := []string{}
+	for name := range w.handlers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// run runs the job while extending its lease, and removes it when done.
+// Failed jobs are also removed since retries are queued as new jobs.
+// Jobs without handler are released for other instances, not removed.
+func (w *DBWorker) run(ctx context.Context, j *models.Job) {
+	w.mutex.RLock()
+	h, ok := w.handlers[j.Handler]
+	w.mutex.RUnlock()
+
+	if !ok {
+		logger.Errorf("no handler mapped for job %v. released", j)
+		if err := j.Release(); err != nil {
+			logger.Errorf("could not release job %v: %v", j, err)
+		}
+		return
+	}
+	if args, err := j.ArgsMap(); err != nil {
+		logger.Errorf("could not parse arguments of job %v: %v. dropped", j, err)
+	} else {
+		done := make(chan struct{})
+		go w.keepLease(ctx, j, done)
+		if err := h(args); err != nil {
+			logger.Errorf("job %v returned error: %v", j, err)
+		}
+		close(done)
+	}
+	if err := j.Done(); err != nil {
+		logger.Errorf("could not remove job %v: %v", j, err)
+	}
+}
+
+// keepLease extends the lease of the running job until it is done.
+func (w *DBWorker) keepLease(ctx context.Context, j *models.Job, done chan struct{}) {
+	ticker := time.NewTicker(w.Lease / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := j.ExtendLease(w.Lease); err != nil {
+				logger.Errorf("could not extend lease of job %v: %v", j, err)
+			}
+		}
+	}
+}
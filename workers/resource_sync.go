@@ -3,9 +3,12 @@ package workers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v5"
 	"github.com/gofrs/uuid"
 	"github.com/hyeoncheon/spec"
 	"github.com/jinzhu/copier"
@@ -18,9 +21,9 @@ import (
 
 // constants belongs to this worker
 const (
-	WorkerResourceSync             = "worker.ResourceSync"
-	workerResourceSyncInitailDelay = 10 * time.Minute
-	workerResourceSyncRunPeriod    = 24 * time.Hour
+	WorkerResourceSync            = "worker.ResourceSync"
+	workerResourceSyncSchedule    = "0 2 * * *"
+	workerResourceSyncGracePeriod = 72 * time.Hour
 )
 
 // ResourceSync is worker to sync resources via plugin in batch mode.
@@ -31,15 +34,15 @@ func init() {
 		HandlerHolder: &ResourceSync{},
 		Name:          WorkerResourceSync,
 		IsPeriodic:    true,
-		InitailDelay:  workerResourceSyncInitailDelay,
-		RunPeriod:     workerResourceSyncRunPeriod,
+		Schedule:      workerResourceSyncSchedule,
+		Retry:         DefaultRetryPolicy,
 	})
 }
 
 // Handler implements HandlerHolder
 func (j ResourceSync) Handler(args worker.Args) error {
 	providerID := args["provider_id"]
-	return syncResources(providerID)
+	return syncResources(providerID, args[argScheduled] == true)
 }
 
 // Reset implements HandlerHolder
@@ -49,73 +52,228 @@ func (j ResourceSync) Reset() error {
 
 //*** local task functions
 
-func syncResources(id interface{}) error {
-	providers := &models.Providers{}
-	query := models.DB.Q()
-	if id != nil {
-		query = query.Where("id = ?", id)
+// syncResources queues a job for each provider if id is not given, or
+// syncs resources of the provider.
+func syncResources(id interface{}, scheduled bool) error {
+	if id == nil {
+		err := fanOut(WorkerResourceSync, scheduled)
+		retireResources()
+		return err
 	}
-	if err := query.All(providers); err != nil {
-		logger.Errorf("database error: %v", err)
+	provider := &models.Provider{}
+	if err := models.DB.Find(provider, id); err != nil {
+		logger.Errorf("could not find provider %v: %v", id, err)
 		return err
 	}
-	logger.Infof("sync resources via plugins for %v", *providers)
+	release := acquire()
+	defer release()
+	logger.Debugf("sync resources for %v...", provider)
 
-	for _, provider := range *providers {
-		logger.Debugf("sync resources for %v...", provider)
+	run := models.StartSyncRun(provider.ID, models.SyncResource)
+	err := syncProviderResources(provider, run)
+	if err := run.Finish(err); err != nil {
+		logger.Errorf("could not save sync run: %v", err)
+	}
+	if err != nil {
+		notifySyncFailed(provider, "resource", err)
+		return err
+	}
+	logger.Debugf("resources for %v are synced successfully", provider)
+	return nil
+}
 
-		plugin, err := plugins.GetPlugin(provider.Provider, "provider")
+// syncProviderResources syncs resources of the provider and counts the
+// changes on the run.
+func syncProviderResources(provider *models.Provider, run *models.SyncRun) error {
+	plugin, err := plugins.GetPlugin(provider.Provider, "provider", plugins.CapResources)
+	if err != nil {
+		logger.Errorf("could not find plugin %v: %v", provider.Provider, err)
+		return err
+	}
+	user, pass, err := provider.Credentials()
+	if err != nil {
+		logger.Errorf("could not get credentials of %v: %v", provider, err)
+		return err
+	}
+	resources, err := plugin.GetResources(user, pass)
+	if err != nil {
+		logger.Errorf("could not get resources via plugin: %v", err)
+		return err
+	}
+	logger.Debugf("got %v resources. create/update...", len(resources))
+
+	// all changes for the provider are written in a transaction. if any of
+	// them fails, the previous snapshot remains as it was.
+	changeSet := models.NewChangeSet(provider.ID)
+	err = models.DB.Transaction(func(tx *pop.Connection) error {
+		return applyResources(tx, provider, resources, changeSet, run.ID)
+	})
+	if err != nil {
+		logger.Errorf("resource sync for %v was rolled back: %v", provider, err)
+		return err
+	}
+	run.Count(changeSet)
+	raiseAlerts(changeSet.Events)
+
+	logger.Infof("resources for %v: %v added, %v modified, %v removed, %v unchanged",
+		provider, changeSet.Added, changeSet.Modified, changeSet.Removed, changeSet.Unchanged)
+	return nil
+}
+
+// applyResources writes resources from the plugin and records changes on
+// the change set. It returns error on the first failure to roll back.
+func applyResources(tx *pop.Connection, provider *models.Provider, resources []interface{}, changeSet *models.ChangeSet, runID uuid.UUID) error {
+	if err := tx.Load(provider, "Resources"); err != nil {
+		return fmt.Errorf("could not load stored resources: %v", err)
+	}
+	seen := map[uuid.UUID]bool{}
+	var ids []uuid.UUID
+	for _, r := range resources {
+		jr, err := json.Marshal(r)
 		if err != nil {
-			logger.Errorf("could not find plugin %v: %v", provider.Provider, err)
+			logger.Errorf("unrecognized data format: %T", r)
+			return errors.New("cloud not recognize data format")
+		}
+		logger.Debugf("------ found: %v", string(jr))
+		re := &spec.HoncheonuiResource{}
+		if err := json.Unmarshal(jr, re); err != nil {
+			logger.Errorf("error: %v", err)
+			return errors.New("cloud not recognize data format")
+		}
+		res := &models.Resource{}
+		copier.Copy(res, re)
+		// universally unique identifier, uuid is not perfectly uniq but almost.
+		// but we can assume it is uniq anyway.
+		// buffalo/pop uses uuid version 4 based on random number generator and
+		// softlayer seems to use real random string as uuid. :-(
+		if res.UUID != uuid.Nil {
+			res.ID = res.UUID
+		}
+		existing := res.Existing(tx)
+		if existing != nil {
+			res.ID = existing.ID
+			res.CreatedAt = existing.CreatedAt
+		}
+		if seen[res.ID] {
+			logger.Warnf("duplicated resource %v (%v). skipped", res, res.ID)
 			continue
 		}
-		resources, err := plugin.GetResources(provider.User, provider.Pass)
+
+		kind, details, err := syncResource(tx, runID, res, existing, re)
 		if err != nil {
-			logger.Errorf("could not get resources via plugin: %v", err)
+			logger.Errorf("---- resource: %v", res.JSON())
+			return fmt.Errorf("could not save resource %v: %v", res, err)
 		}
-		logger.Debugf("got %v resources. create/update...", len(resources))
-
-		var ids []uuid.UUID
-		for _, r := range resources {
-			res := &models.Resource{}
-			if jr, err := json.Marshal(r); err == nil {
-				logger.Debugf("------ found: %v", string(jr))
-				re := &spec.HoncheonuiResource{}
-				if err := json.Unmarshal(jr, re); err != nil {
-					logger.Errorf("error: %v", err)
-					return errors.New("cloud not recognize data format")
-				}
-				copier.Copy(res, re)
-				// universally unique identifier, uuid is not perfectly uniq but almost.
-				// but we can assume it is uniq anyway.
-				// buffalo/pop uses uuid version 4 based on random number generator and
-				// softlayer seems to use real random string as uuid. :-(
-				if res.UUID != uuid.Nil {
-					res.ID = res.UUID
-				}
-				ids = append(ids, res.ID)
-				if err := res.Save(); err != nil {
-					logger.Errorf("saving error: %v", err)
-					logger.Errorf("---- resource: %v", res.JSON())
-				}
-				for k, v := range re.Attributes {
-					res.AddAttribute(k, v)
-				}
-				if err := res.LinkTags(re.Tags); err != nil {
-					logger.Debugf("problem on mapping tags")
-				}
-				if err := res.LinkUsers(re.UserIDs); err != nil {
-					logger.Debugf("problem on mapping users")
-				}
-				// TODO: support IntegerAttributes
-				logger.Debugf("------ re.IntegerAttributes: %v", re.IntegerAttributes)
+		seen[res.ID] = true
+		ids = append(ids, res.ID)
+		changeSet.Add(kind, res, details...)
+		if existing != nil {
+			changeSet.Events = append(changeSet.Events, models.DetectResourceEvents(existing, res, attributesOf(re))...)
+		}
+	}
+	for _, res := range provider.Resources {
+		if !seen[res.ID] {
+			if res.IsActive() {
+				changeSet.Events = append(changeSet.Events, models.ResourceEvent{ResourceID: res.ID, Condition: models.AlertMissing})
+			}
+			if err := res.MarkMissing(tx); err != nil {
+				return fmt.Errorf("could not mark %v as missing: %v", res, err)
 			}
+			changeSet.Add(models.ChangeRemoved, &res)
 		}
-		if err := provider.LinkResources(ids); err != nil {
-			logger.Debugf("problem on mapping provider")
+	}
+	if err := provider.LinkResources(tx, ids); err != nil {
+		return fmt.Errorf("could not link resources: %v", err)
+	}
+	if err := changeSet.Save(tx); err != nil {
+		return fmt.Errorf("could not save change set: %v", err)
+	}
+	return nil
+}
+
+// retireResources retires resources missing longer than grace period and
+// purges retired resources if HCU_RESOURCE_PURGE_AFTER is set.
+func retireResources() {
+	grace := durationFromEnv("HCU_RESOURCE_GRACE_PERIOD", workerResourceSyncGracePeriod)
+	if count, err := models.RetireResources(grace); err != nil {
+		logger.Errorf("could not retire missing resources: %v", err)
+	} else if count > 0 {
+		logger.Infof("%v resources missing longer than %v were retired", count, grace)
+	}
+
+	if envy.Get("HCU_RESOURCE_PURGE_AFTER", "") == "" {
+		return
+	}
+	after := durationFromEnv("HCU_RESOURCE_PURGE_AFTER", 30*24*time.Hour)
+	if count, err := models.PurgeResources(after); err != nil {
+		logger.Errorf("could not purge retired resources: %v", err)
+	} else if count > 0 {
+		logger.Infof("%v resources retired longer than %v were purged", count, after)
+	}
+}
+
+// syncResource writes the resource only if it is new or changed from the
+// existing one, and returns the kind of change with its details. Attribute
+// changes are recorded with the sync run.
+func syncResource(tx *pop.Connection, runID uuid.UUID, res, existing *models.Resource, re *spec.HoncheonuiResource) (string, []string, error) {
+	attrs := attributesOf(re)
+	res.State = models.ResourceActive
+	if existing == nil {
+		if err := res.Save(tx); err != nil {
+			return "", nil, err
+		}
+		if err := res.SyncAttributes(tx, runID, attrs); err != nil {
+			return "", nil, err
+		}
+		if err := res.LinkTags(tx, re.Tags); err != nil {
+			return "", nil, err
+		}
+		if err := res.LinkUsers(tx, re.UserIDs); err != nil {
+			return "", nil, err
 		}
+		return models.ChangeAdded, nil, nil
+	}
 
-		logger.Debugf("resources for %v are synced successfully", provider)
+	res.StateChangedAt = existing.StateChangedAt
+	if !existing.IsActive() {
+		res.StateChangedAt = time.Now()
 	}
-	return nil
+	diff := existing.Diff(res, re.Tags, attrs)
+	if len(diff.Fields) > 0 {
+		if err := res.Save(tx); err != nil {
+			return "", nil, err
+		}
+	}
+	if diff.Attributes {
+		if err := res.SyncAttributes(tx, runID, attrs); err != nil {
+			return "", nil, err
+		}
+	}
+	if diff.Tags {
+		if err := res.LinkTags(tx, re.Tags); err != nil {
+			return "", nil, err
+		}
+	}
+	// LinkUsers writes changed links only
+	if err := res.LinkUsers(tx, re.UserIDs); err != nil {
+		return "", nil, err
+	}
+	if diff.IsEmpty() {
+		return models.ChangeUnchanged, nil, nil
+	}
+	return models.ChangeModified, diff.Details, nil
+}
+
+// attributesOf returns string and integer attributes of the resource with
+// their types. String attributes are kept as strings even if they look
+// like numbers.
+func attributesOf(re *spec.HoncheonuiResource) models.AttributeValues {
+	attrs := models.AttributeValues{}
+	for k, v := range re.Attributes {
+		attrs[k] = models.StringValue(v)
+	}
+	for k, v := range re.IntegerAttributes {
+		attrs[k] = models.IntegerValue(v)
+	}
+	return attrs
 }
@@ -0,0 +1,43 @@
+package workers
+
+import (
+	"os"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+func (ms *ModelSuite) Test_SyncResources_Fake() {
+	provider := ms.fakeProvider()
+
+	// the resource with duplicated uuid is skipped
+	ms.NoError(syncResources(provider.ID, false))
+	count, err := ms.DB.Count(&models.Resources{})
+	ms.NoError(err)
+	ms.Equal(3, count)
+
+	run := provider.LastSyncRun(models.SyncResource)
+	ms.NotNil(run)
+	ms.False(run.IsFailed())
+	ms.Equal(3, run.Added)
+	ms.NotEqual(uuid.Nil, run.ChangeSetID)
+
+	res := &models.Resource{}
+	ms.NoError(ms.DB.Where("original_id = ?", "1001").First(res))
+	ms.NoError(ms.DB.Load(res, "Tags", "Attributes"))
+	ms.Len(res.Tags, 2)
+	ms.Equal(models.ResourceActive, res.State)
+
+	// resources disappeared from the provider are marked as missing
+	fixture := ms.fakeFixture(1)
+	defer os.Remove(fixture)
+	ms.useFake(fixture)
+	ms.NoError(syncResources(provider.ID, false))
+
+	resources := &models.Resources{}
+	ms.NoError(ms.DB.Where("state = ?", models.ResourceMissing).All(resources))
+	ms.Len(*resources, 2)
+	ms.NoError(ms.DB.Reload(res))
+	ms.Equal(models.ResourceActive, res.State)
+}
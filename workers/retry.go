@@ -0,0 +1,112 @@
+package workers
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v5"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+const argAttempt = "attempt"
+
+// RetryPolicy is a policy for retrying failed jobs of a worker. Delay of
+// retries grows exponentially from Backoff up to MaxBackoff, and is spread
+// by Jitter, a fraction of the delay.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Jitter      float64
+}
+
+// DefaultRetryPolicy is the retry policy for provider jobs.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	Backoff:     1 * time.Minute,
+	MaxBackoff:  30 * time.Minute,
+	Jitter:      0.2,
+}
+
+// Delay returns the delay before the next attempt of the failed attempt.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt; i++ {
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// perform runs the handler of the worker and retries it on failure as the
+// retry policy. Jobs failed on the last attempt are kept as dead letters.
+// Workers without retry policy, such as system workers, are run as is.
+func (w *Worker) perform(args worker.Args) error {
+	err := w.run(args)
+	if err == nil || w.Retry.MaxAttempts < 1 {
+		return err
+	}
+
+	attempt := attemptOf(args)
+	if attempt < w.Retry.MaxAttempts {
+		next := worker.Args{}
+		for k, v := range args {
+			next[k] = v
+		}
+		next[argAttempt] = attempt + 1
+		delay := w.Retry.Delay(attempt)
+		logger.Warnf("%v failed on attempt %v/%v: %v. retry in %v",
+			w.Name, attempt, w.Retry.MaxAttempts, err, delay)
+		qerr := Queue(w.Name, next, delay)
+		if qerr == nil {
+			return err
+		}
+		logger.Errorf("could not queue retry of %v: %v", w.Name, qerr)
+	}
+
+	logger.Errorf("%v failed permanently after %v attempts: %v", w.Name, attempt, err)
+	delete(args, argAttempt)
+	letter := models.NewDeadLetter(w.Name, args, attempt, err)
+	if verrs, err := models.DB.ValidateAndCreate(letter); err != nil || verrs.HasAny() {
+		logger.Errorf("could not save dead letter of %v: %v %v", w.Name, err, verrs)
+	}
+	return err
+}
+
+// attemptOf returns the attempt number of the job. The first attempt does
+// not have it on its arguments.
+func attemptOf(args worker.Args) int {
+	switch n := args[argAttempt].(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 1
+}
+
+// Requeue queues the job of the dead letter again as a new job and removes
+// the dead letter within the transaction. The job is queued first so the
+// dead letter is kept if it could not be queued, and it is queued within
+// the transaction too if the queue is persistent. The caller should roll
+// the transaction back if it returns an error.
+func Requeue(tx *pop.Connection, letter *models.DeadLetter) error {
+	args, err := letter.ArgsMap()
+	if err != nil {
+		return err
+	}
+	if err := QueueTx(tx, letter.Worker, args, 0); err != nil {
+		return err
+	}
+	return tx.Destroy(letter)
+}
@@ -0,0 +1,136 @@
+package workers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+func Test_RetryPolicy_Delay(t *testing.T) {
+	r := require.New(t)
+
+	p := RetryPolicy{MaxAttempts: 5, Backoff: time.Minute, MaxBackoff: 5 * time.Minute}
+	r.Equal(1*time.Minute, p.Delay(1))
+	r.Equal(2*time.Minute, p.Delay(2))
+	r.Equal(4*time.Minute, p.Delay(3))
+	r.Equal(5*time.Minute, p.Delay(4))
+	r.Equal(5*time.Minute, p.Delay(10))
+
+	p.Jitter = 0.2
+	for i := 0; i < 100; i++ {
+		d := p.Delay(2)
+		r.True(d >= 96*time.Second && d <= 144*time.Second, d)
+	}
+}
+
+func Test_AttemptOf(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(1, attemptOf(nil))
+	r.Equal(1, attemptOf(worker.Args{"provider_id": "x"}))
+	r.Equal(3, attemptOf(worker.Args{argAttempt: 3}))
+	r.Equal(2, attemptOf(worker.Args{argAttempt: float64(2)}))
+}
+
+// failingWorker registers a worker which always fails with the retry
+// policy and returns it with the backend recording queued jobs. The
+// caller should call the returned function to restore them.
+func failingWorker(maxAttempts int) (*Worker, *recordingWorker, func()) {
+	savedWorkers, savedBackend := workers, aw
+	w := &Worker{
+		HandlerHolder: &testHandler{err: errors.New("plugin call timed out")},
+		Name:          "worker.Failing",
+		Mode:          ModeEnabled,
+		Retry:         RetryPolicy{MaxAttempts: maxAttempts, Backoff: time.Minute},
+	}
+	workers = Workers{}
+	RegisterWorkers(w)
+	rec := &recordingWorker{handlers: map[string]worker.Handler{}}
+	aw = rec
+	return w, rec, func() { workers, aw = savedWorkers, savedBackend }
+}
+
+func (ms *ModelSuite) Test_Worker_Perform() {
+	w, rec, restore := failingWorker(2)
+	defer restore()
+
+	// failed attempt is queued again with the next attempt
+	ms.Error(w.perform(worker.Args{"provider_id": "1"}))
+	ms.Len(rec.queued, 1)
+	ms.Equal(w.Name, rec.queued[0].Handler)
+	ms.Equal(2, rec.queued[0].Args[argAttempt])
+	ms.Equal("1", rec.queued[0].Args["provider_id"])
+	count, err := ms.DB.Count(&models.DeadLetter{})
+	ms.NoError(err)
+	ms.Equal(0, count)
+
+	// the last attempt is kept as a dead letter without its attempt
+	ms.Error(w.perform(rec.queued[0].Args))
+	ms.Len(rec.queued, 1)
+	letters := &models.DeadLetters{}
+	ms.NoError(ms.DB.All(letters))
+	ms.Len(*letters, 1)
+	letter := (*letters)[0]
+	ms.Equal(w.Name, letter.Worker)
+	ms.Equal(2, letter.Attempts)
+	ms.Equal("plugin call timed out", letter.Error)
+	ms.Equal(`{"provider_id":"1"}`, letter.Args)
+}
+
+func (ms *ModelSuite) Test_Requeue() {
+	w, rec, restore := failingWorker(1)
+	defer restore()
+
+	letter := models.NewDeadLetter(w.Name, map[string]interface{}{"provider_id": "1"}, 1, errors.New("failed"))
+	ms.NoError(ms.DB.Create(letter))
+	ms.NoError(Requeue(ms.DB, letter))
+	ms.Len(rec.queued, 1)
+	ms.Equal("1", rec.queued[0].Args["provider_id"])
+	count, err := ms.DB.Count(&models.DeadLetter{})
+	ms.NoError(err)
+	ms.Equal(0, count)
+
+	// dead letters of unknown workers could not be queued and are kept
+	letter = models.NewDeadLetter("worker.Unknown", nil, 1, errors.New("failed"))
+	ms.NoError(ms.DB.Create(letter))
+	ms.Error(Requeue(ms.DB, letter))
+	ms.Len(rec.queued, 1)
+	count, err = ms.DB.Count(&models.DeadLetter{})
+	ms.NoError(err)
+	ms.Equal(1, count)
+}
+
+func (ms *ModelSuite) Test_Requeue_Persistent() {
+	w, _, restore := failingWorker(1)
+	defer restore()
+	aw = &DBWorker{Owner: "test", handlers: map[string]worker.Handler{}}
+
+	letter := models.NewDeadLetter(w.Name, map[string]interface{}{"provider_id": "1"}, 1, errors.New("failed"))
+	ms.NoError(ms.DB.Create(letter))
+	count := func(model interface{}) int {
+		n, err := ms.DB.Count(model)
+		ms.NoError(err)
+		return n
+	}
+
+	// the job is queued within the transaction with the removal
+	err := ms.DB.Transaction(func(tx *pop.Connection) error {
+		ms.NoError(Requeue(tx, letter))
+		return errors.New("rolled back")
+	})
+	ms.Error(err)
+	ms.Equal(1, count(&models.DeadLetter{}))
+	ms.Equal(0, count(&models.Job{}))
+
+	ms.NoError(ms.DB.Transaction(func(tx *pop.Connection) error {
+		return Requeue(tx, letter)
+	}))
+	ms.Equal(0, count(&models.DeadLetter{}))
+	ms.Equal(1, count(&models.Job{}))
+}
@@ -0,0 +1,123 @@
+package workers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gofrs/uuid"
+
+	"github.com/hyeoncheon/honcheonui/models"
+	"github.com/hyeoncheon/honcheonui/utils"
+)
+
+const (
+	argScheduled  = "scheduled"
+	argState      = "state"
+	schedulerTick = 1 * time.Minute
+)
+
+// runScheduled runs jobs of the worker due at the time. The default
+// schedule runs the job for all providers except ones have their own
+// schedules, and the overrides run the job for each provider.
+// It takes the next run times of the previous tick and returns new ones.
+func (w *Worker) runScheduled(now time.Time, state map[string]interface{}) map[string]interface{} {
+	schedules, err := models.AllSchedules()
+	if err != nil {
+		logger.Errorf("could not get schedules. use default: %v", err)
+	}
+
+	// next runs are computed even if paused so it does not run missed ones
+	// when resumed.
+	paused := schedules.IsPaused(w.Name)
+	next := map[string]interface{}{}
+	if isDue(state, next, w.Name, w.DefaultSchedule(schedules), now) && !paused {
+		if err := Run(w.Name, worker.Args{argScheduled: true}); err != nil {
+			logger.Errorf("could not run scheduled %v: %v", w.Name, err)
+		}
+	}
+	for _, s := range schedules.Overrides(w.Name) {
+		if isDue(state, next, w.Name+"/"+s.ProviderID.String(), s.Cron, now) && !paused && !s.Paused {
+			err := Run(w.Name, worker.Args{"provider_id": s.ProviderID.String()})
+			if err != nil {
+				logger.Errorf("could not run scheduled %v for %v: %v", w.Name, s.ProviderID, err)
+			}
+		}
+	}
+	return next
+}
+
+// isDue returns true if the schedule of the target is due at the time,
+// and records its next run on next. New or changed schedules are just
+// recorded since the state is keyed by the target and the expression.
+func isDue(state, next map[string]interface{}, target, cron string, now time.Time) bool {
+	c, err := utils.ParseCron(cron)
+	if err != nil {
+		logger.Errorf("invalid schedule %v for %v: %v", cron, target, err)
+		return false
+	}
+	key := stateKey(target, cron)
+	at, ok := unixOf(state[key])
+	if !ok {
+		next[key] = c.Next(now).Unix()
+		logger.Infof("%v is scheduled at %v (%v)", target, c.Next(now), cron)
+		return false
+	}
+	if now.Unix() < at {
+		next[key] = at
+		return false
+	}
+	next[key] = c.Next(now).Unix()
+	return true
+}
+
+// stateKey returns the key of the target and its schedule on the state.
+func stateKey(target, cron string) string {
+	return target + " " + cron
+}
+
+// unixOf returns unix time from the state. Numbers are float64 if the
+// state was stored on the persistent queue.
+func unixOf(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// DefaultSchedule returns the default schedule of the worker stored on the
+// database or its built-in schedule.
+func (w *Worker) DefaultSchedule(schedules *models.Schedules) string {
+	if schedules != nil {
+		if s := schedules.For(w.Name, uuid.Nil); s != nil {
+			return s.Cron
+		}
+	}
+	return w.Schedule
+}
+
+// NextRun returns the next run time of the schedule from now.
+func (w *Worker) NextRun(cron string) time.Time {
+	c, err := utils.ParseCron(cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return c.Next(time.Now())
+}
+
+// ScheduledWorkers returns scheduled workers sorted by name.
+func ScheduledWorkers() []*Worker {
+	list := []*Worker{}
+	for _, w := range workers {
+		if w.IsPeriodic && w.Schedule != "" {
+			list = append(list, w)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
@@ -0,0 +1,52 @@
+package workers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IsDue(t *testing.T) {
+	r := require.New(t)
+
+	cron := "0 2 * * *"
+	key := "worker.Test " + cron
+	now := time.Date(2026, 10, 18, 1, 59, 0, 0, time.UTC)
+	at := time.Date(2026, 10, 18, 2, 0, 0, 0, time.UTC)
+
+	// the first check just records the next run
+	next := map[string]interface{}{}
+	r.False(isDue(nil, next, "worker.Test", cron, now))
+	r.Equal(at.Unix(), next[key])
+
+	state, next := next, map[string]interface{}{}
+	r.False(isDue(state, next, "worker.Test", cron, now.Add(30*time.Second)))
+	r.Equal(at.Unix(), next[key])
+
+	// numbers are float64 if the state is from the persistent queue
+	state, next = map[string]interface{}{key: float64(at.Unix())}, map[string]interface{}{}
+	r.True(isDue(state, next, "worker.Test", cron, now.Add(time.Minute)))
+	r.Equal(at.AddDate(0, 0, 1).Unix(), next[key])
+
+	state, next = next, map[string]interface{}{}
+	r.False(isDue(state, next, "worker.Test", cron, now.Add(2*time.Minute)))
+
+	// changed schedule is recorded again
+	next = map[string]interface{}{}
+	r.False(isDue(state, next, "worker.Test", "@hourly", now.Add(2*time.Minute)))
+	r.Equal(at.Add(time.Hour).Unix(), next["worker.Test @hourly"])
+	_, ok := next[key]
+	r.False(ok)
+
+	r.False(isDue(state, next, "worker.Test", "every day", now))
+}
+
+func Test_Worker_DefaultSchedule(t *testing.T) {
+	r := require.New(t)
+
+	w := &Worker{Name: "worker.Test", IsPeriodic: true, Schedule: "0 2 * * *"}
+	r.Equal("0 2 * * *", w.DefaultSchedule(nil))
+	r.False(w.NextRun(w.Schedule).IsZero())
+	r.True(w.NextRun("every day").IsZero())
+}
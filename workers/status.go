@@ -0,0 +1,241 @@
+package workers
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gofrs/uuid"
+
+	"github.com/hyeoncheon/honcheonui/models"
+)
+
+// results of the last run
+const (
+	ResultRunning   = "running"
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+)
+
+// errors for worker controls
+var (
+	ErrNoSuchWorker = errors.New("could not find worker")
+	ErrNotScheduled = errors.New("worker is not scheduled")
+)
+
+// Status is a snapshot of the status of a worker. Run statistics and the
+// next run are shared by all instances if the queue is persistent. Queued
+// counts are of this instance.
+type Status struct {
+	Name         string        `json:"name"`
+	IsPeriodic   bool          `json:"is_periodic"`
+	IsSystem     bool          `json:"is_system"`
+	Schedule     string        `json:"schedule"`
+	Mode         string        `json:"mode"`
+	Paused       bool          `json:"paused"`
+	CountQueued  int32         `json:"count_queued"`
+	LastQueuedAt time.Time     `json:"last_queued_at"`
+	CountRun     int           `json:"count_run"`
+	LastRunAt    time.Time     `json:"last_run_at"`
+	LastResult   string        `json:"last_result"`
+	LastError    string        `json:"last_error"`
+	LastDuration time.Duration `json:"last_duration"`
+	QueueDepth   int           `json:"queue_depth"`
+	NextRunAt    time.Time     `json:"next_run_at"`
+}
+
+// run runs the handler of the worker and records the result on the
+// database.
+func (w *Worker) run(args worker.Args) error {
+	w.mutex.Lock()
+	w.running++
+	if w.pending > 0 {
+		w.pending--
+	}
+	w.mutex.Unlock()
+
+	started := time.Now()
+	err := w.Handler(args)
+
+	w.mutex.Lock()
+	w.running--
+	w.mutex.Unlock()
+	if rerr := models.RecordWorkerRun(w.Name, started, time.Since(started), err); rerr != nil {
+		logger.Errorf("could not record run of %v: %v", w.Name, rerr)
+	}
+	return err
+}
+
+// Status returns the status of the worker with its schedules.
+func (w *Worker) Status(schedules *models.Schedules) Status {
+	w.mutex.Lock()
+	s := Status{
+		Name:         w.Name,
+		IsPeriodic:   w.IsPeriodic,
+		IsSystem:     w.Name == workerRepeater,
+		Schedule:     w.Schedule,
+		Mode:         w.Mode,
+		CountQueued:  w.CountQueued,
+		LastQueuedAt: w.LastQueuedAt,
+		QueueDepth:   int(w.pending),
+	}
+	running := w.running > 0
+	w.mutex.Unlock()
+
+	if run := models.FindWorkerRun(w.Name); run != nil {
+		s.CountRun = run.CountRun
+		s.LastRunAt = run.LastRunAt
+		s.LastError = run.LastError
+		s.LastDuration = run.LastDuration
+	}
+	if isPersistent() {
+		if n, err := models.DB.Where("handler = ?", w.Name).Count(&models.Job{}); err == nil {
+			s.QueueDepth = n
+		}
+		// claimed jobs are running on any instance
+		n, err := models.DB.Where("handler = ? AND locked_by != ? AND locked_until > ?",
+			w.Name, "", time.Now()).Count(&models.Job{})
+		running = running || (err == nil && n > 0)
+	}
+	switch {
+	case running:
+		s.LastResult = ResultRunning
+	case s.LastError != "":
+		s.LastResult = ResultFailed
+	case s.CountRun > 0:
+		s.LastResult = ResultSucceeded
+	}
+
+	if schedules == nil {
+		schedules = &models.Schedules{}
+	}
+	args, runAt := w.repeaterJob()
+	if w.IsPeriodic && w.Schedule != "" {
+		s.Schedule = w.DefaultSchedule(schedules)
+		s.Paused = schedules.IsPaused(w.Name)
+		if !s.Paused && w.Mode == ModeEnabled {
+			s.NextRunAt = w.NextRun(s.Schedule)
+			// the schedule is run at the time kept by the repeater
+			state, _ := args[argState].(map[string]interface{})
+			if at, ok := unixOf(state[stateKey(w.Name, s.Schedule)]); ok {
+				s.NextRunAt = time.Unix(at, 0)
+			}
+		}
+	} else if w.IsPeriodic && w.RunPeriod > 0 {
+		s.Schedule = "@every " + w.RunPeriod.String()
+		if w.Mode == ModeEnabled {
+			s.NextRunAt = runAt
+		}
+	}
+	return s
+}
+
+// repeaterJob returns the arguments of the pending repeater job of the
+// worker and when it is run. The job is looked up on the database if the
+// queue is persistent since it could be queued by another instance.
+func (w *Worker) repeaterJob() (map[string]interface{}, time.Time) {
+	if isPersistent() {
+		key := &models.Job{}
+		key.SetUniqueKey(DefaultQueue, workerRepeater, w.Name)
+		job := &models.Job{}
+		if err := models.DB.Where("unique_key = ?", key.UniqueKey).First(job); err != nil {
+			return nil, time.Time{}
+		}
+		args, err := job.ArgsMap()
+		if err != nil {
+			logger.Errorf("could not get arguments of repeater of %v: %v", w.Name, err)
+		}
+		return args, job.RunAt
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.repeaterArgs, w.repeaterAt
+}
+
+// IsRunning returns true if the worker is running now.
+func (s Status) IsRunning() bool {
+	return s.LastResult == ResultRunning
+}
+
+// Statuses returns statuses of all registered workers sorted by name.
+func Statuses() []Status {
+	schedules, err := models.AllSchedules()
+	if err != nil {
+		logger.Errorf("could not get schedules: %v", err)
+	}
+	list := []Status{}
+	for _, w := range workers {
+		list = append(list, w.Status(schedules))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// GetStatus returns the status of the worker.
+func GetStatus(name string) (Status, error) {
+	w := workers[name]
+	if w == nil {
+		return Status{}, ErrNoSuchWorker
+	}
+	schedules, err := models.AllSchedules()
+	if err != nil {
+		logger.Errorf("could not get schedules: %v", err)
+	}
+	return w.Status(schedules), nil
+}
+
+// RunNow queues the worker to be run immediately. System workers could
+// not be run manually.
+func RunNow(name string) error {
+	if name == workerRepeater {
+		return ErrNoSuchWorker
+	}
+	return Run(name, nil)
+}
+
+// Pause pauses scheduled runs of the worker. It could be still run
+// manually.
+func Pause(name string) error {
+	return setPaused(name, true)
+}
+
+// Resume resumes scheduled runs of the worker.
+func Resume(name string) error {
+	return setPaused(name, false)
+}
+
+// setPaused stores paused state on the default schedule of the worker so
+// it is shared by all instances.
+func setPaused(name string, paused bool) error {
+	w := workers[name]
+	if w == nil {
+		return ErrNoSuchWorker
+	}
+	if !w.IsPeriodic || w.Schedule == "" {
+		return ErrNotScheduled
+	}
+	schedule := &models.Schedule{}
+	err := models.DB.Where("worker = ? AND provider_id = ?", name, uuid.Nil).First(schedule)
+	if err != nil {
+		schedule = &models.Schedule{Worker: name, Cron: w.Schedule}
+	}
+	schedule.Paused = paused
+	verrs, err := models.DB.ValidateAndSave(schedule)
+	if err != nil {
+		return err
+	}
+	if verrs.HasAny() {
+		return verrs
+	}
+	logger.Infof("worker %v is paused: %v", name, paused)
+	return nil
+}
+
+// isPersistent returns true if the queue is persistent.
+func isPersistent() bool {
+	_, ok := aw.(*DBWorker)
+	return ok
+}
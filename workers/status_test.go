@@ -0,0 +1,98 @@
+package workers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/stretchr/testify/require"
+)
+
+type testHandler struct {
+	err error
+}
+
+func (h testHandler) Handler(worker.Args) error {
+	return h.err
+}
+
+func (h testHandler) Reset() error {
+	return nil
+}
+
+func (ms *ModelSuite) Test_Worker_Status() {
+	savedWorkers, savedBackend := workers, aw
+	defer func() { workers, aw = savedWorkers, savedBackend }()
+	aw = &recordingWorker{handlers: map[string]worker.Handler{}}
+
+	w := &Worker{
+		HandlerHolder: &testHandler{},
+		Name:          "worker.Test",
+		IsPeriodic:    true,
+		Schedule:      "0 2 * * *",
+		Mode:          ModeEnabled,
+	}
+	periodic := &Worker{
+		HandlerHolder: &testHandler{},
+		Name:          "worker.Periodic",
+		IsPeriodic:    true,
+		RunPeriod:     time.Hour,
+		Mode:          ModeEnabled,
+	}
+	workers = Workers{}
+	RegisterWorkers(&Worker{HandlerHolder: &repeater{}, Name: workerRepeater}, w, periodic)
+
+	s := w.Status(nil)
+	ms.Equal("0 2 * * *", s.Schedule)
+	ms.Equal("", s.LastResult)
+	ms.False(s.NextRunAt.IsZero())
+	ms.False(s.IsSystem)
+
+	w.Mode = ModeManual
+	ms.True(w.Status(nil).NextRunAt.IsZero())
+	w.Mode = ModeEnabled
+
+	ms.NoError(w.run(nil))
+	s = w.Status(nil)
+	ms.Equal(ResultSucceeded, s.LastResult)
+	ms.Equal(1, s.CountRun)
+	ms.False(s.LastRunAt.IsZero())
+
+	w.HandlerHolder = &testHandler{err: errors.New("plugin call timed out")}
+	ms.Error(w.run(nil))
+	s = w.Status(nil)
+	ms.Equal(ResultFailed, s.LastResult)
+	ms.Equal("plugin call timed out", s.LastError)
+	ms.Equal(2, s.CountRun)
+
+	// run statistics are shared with other instances
+	other := &Worker{HandlerHolder: &testHandler{}, Name: w.Name, Mode: ModeManual}
+	ms.Equal(2, other.Status(nil).CountRun)
+	ms.Equal(ResultFailed, other.Status(nil).LastResult)
+
+	// next runs are the ones kept by the repeater
+	at := time.Now().Add(time.Hour).Truncate(time.Second)
+	ms.NoError(Queue(workerRepeater, worker.Args{
+		"worker": w.Name,
+		argState: map[string]interface{}{stateKey(w.Name, w.Schedule): at.Unix()},
+	}, schedulerTick))
+	ms.True(at.Equal(w.Status(nil).NextRunAt), w.Status(nil).NextRunAt)
+
+	ms.True(periodic.Status(nil).NextRunAt.IsZero(), "not queued yet")
+	ms.NoError(Queue(workerRepeater, worker.Args{"worker": periodic.Name, "repeat": time.Hour}, 10*time.Minute))
+	next := periodic.Status(nil).NextRunAt
+	ms.True(next.After(time.Now().Add(9*time.Minute)), next)
+	ms.True(next.Before(time.Now().Add(11*time.Minute)), next)
+}
+
+func Test_ModeFor(t *testing.T) {
+	r := require.New(t)
+
+	list := "worker.ResourceSync=enabled, NotificationWatch = disabled,broken,worker.Other=sometimes"
+	r.Equal(ModeEnabled, modeFor("worker.ResourceSync", list, ModeManual))
+	r.Equal(ModeDisabled, modeFor("worker.NotificationWatch", list, ModeManual))
+	r.Equal(ModeManual, modeFor("worker.Other", list, ModeManual))
+	r.Equal(ModeManual, modeFor("worker.Unknown", list, ModeManual))
+	r.Equal(ModeEnabled, modeFor("worker.Unknown", "", ModeEnabled))
+}
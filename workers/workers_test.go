@@ -0,0 +1,73 @@
+package workers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/suite/v3"
+	"github.com/hyeoncheon/spec"
+
+	"github.com/hyeoncheon/honcheonui/models"
+	"github.com/hyeoncheon/honcheonui/plugins"
+	"github.com/hyeoncheon/honcheonui/plugins/fake"
+)
+
+type ModelSuite struct {
+	*suite.Model
+}
+
+func Test_ModelSuite(t *testing.T) {
+	ms := &ModelSuite{suite.NewModel()}
+	suite.Run(t, ms)
+}
+
+// useFake registers the fake provider plugin with the fixture file. If
+// the fixture is empty, builtin default fixture is used.
+func (ms *ModelSuite) useFake(fixture string) {
+	ms.NoError(plugins.Register(plugins.ClassProvider, fake.Name, fake.New(fixture)))
+}
+
+// fakeProvider returns a provider entity for the account of the fake
+// provider with the default fixture.
+func (ms *ModelSuite) fakeProvider() *models.Provider {
+	envy.Set("HCU_SECRET_KEY", "test-secret-key")
+	ms.useFake("")
+
+	provider := &models.Provider{
+		Provider: fake.Name,
+		User:     "fake",
+		Pass:     "fake",
+		GroupID:  "100",
+		UserID:   "1001",
+	}
+	ms.NoError(ms.DB.Create(provider))
+	return provider
+}
+
+// fakeFixture writes a fixture file with the first n resources and only
+// well-formed notifications of the default fixture. The caller should
+// remove the file.
+func (ms *ModelSuite) fakeFixture(n int) string {
+	data, err := ioutil.ReadFile("../plugins/fake/default.json")
+	ms.NoError(err)
+	fixture := &fake.Fixture{}
+	ms.NoError(json.Unmarshal(data, fixture))
+
+	fixture.Resources = fixture.Resources[:n]
+	notes := []json.RawMessage{}
+	for _, raw := range fixture.Notifications {
+		note := spec.HoncheonuiNotification{}
+		if err := json.Unmarshal(raw, &note); err == nil && note.OriginalID != "" {
+			notes = append(notes, raw)
+		}
+	}
+	fixture.Notifications = notes
+
+	f, err := ioutil.TempFile("", "fixture")
+	ms.NoError(err)
+	defer f.Close()
+	ms.NoError(json.NewEncoder(f).Encode(fixture))
+	return f.Name()
+}